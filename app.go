@@ -15,8 +15,15 @@ import (
 const headerBuildVersion = "Build-Version"
 
 type Xecho struct {
-	Echo        *echo.Echo
+	Echo   *echo.Echo
+	Tracer Tracer
+	// NewRelicApp is only set when Config.Tracer is nil and New Relic is used
 	NewRelicApp newrelic.Application
+	conf        Config
+	logger      *logrus.Entry
+	serveErr    chan error
+	health      *healthChecks
+	logLevels   *logLevels
 }
 
 type Config struct {
@@ -32,39 +39,141 @@ type Config struct {
 	ErrorHandler      ErrorHandlerFunc
 	UseDefaultHeaders bool
 	RoutePrefix       string
+	// ErrorFormat replaces ErrorHandler with the built-in handler for one of the ErrorFormat constants,
+	// when not empty
+	ErrorFormat string
+	// ProblemTypeBaseURI is the type prefix of ErrorFormatProblem responses, see ProblemErrorHandler
+	ProblemTypeBaseURI string
+	// ErrorMessages translates error details by Accept-Language when set, see LocalisedErrorHandler
+	ErrorMessages *MessageBundle
+	// DrainTimeout is how long a shutdown waits for in-flight requests to complete
+	DrainTimeout time.Duration
+	// HealthCheckCacheTTL is how long readiness check results are reused before the checks run again
+	HealthCheckCacheTTL time.Duration
+	// DrainDelay is how long Run waits between starting to drain and shutting down,
+	// giving load balancers time to notice readiness failing
+	DrainDelay time.Duration
+	// MetricsEnabled records request metrics and serves them in the Prometheus format on MetricsRoute
+	MetricsEnabled bool
+	MetricsRoute   string
+	// IgnoredErrorStatuses are not noticed as errors on the transaction, so don't count towards the error rate
+	IgnoredErrorStatuses []int
+	// IgnoreClientErrors stops every 4xx status being noticed as an error
+	IgnoreClientErrors bool
+	// AccessLogSkipRoutes are route patterns that are never access logged, given without RoutePrefix
+	AccessLogSkipRoutes []string
+	// AccessLogSkipUserAgents skips access logging requests whose User-Agent contains any of them
+	AccessLogSkipUserAgents []string
+	// AccessLogSkippers skip access logging requests for which any returns true. The default skips
+	// requests to /health from a User-Agent containing HealthChecker, as RequestLoggerMiddleware does.
+	AccessLogSkippers []func(c *Context) bool
+	// AccessLogSampling rules decide what fraction of requests are access logged, see LogSamplingRule.
+	// Routes are given without RoutePrefix, as they are registered.
+	AccessLogSampling []LogSamplingRule
+	// AccessLogSlowThreshold, when set, access logs every request taking at least this long regardless of sampling
+	AccessLogSlowThreshold time.Duration
+	// AccessLogBodies opts routes in to logging their request and response bodies in the access log.
+	// Routes are given without RoutePrefix, as they are registered.
+	AccessLogBodies BodyLogConfig
+	// LogSchema selects the field names used in logs, or the access log line format
+	LogSchema LogSchema
+	// LogHeaders selects the request and response headers that are logged
+	LogHeaders HeaderLogConfig
+	// DebugSecret, when set, lets a request turn on debug mode for itself with an X-Debug-Token
+	// header made by NewDebugToken. It must be at least 32 characters.
+	DebugSecret string
+	// DebugTokenMaxTTL rejects debug tokens that expire further ahead than this, an hour by default
+	DebugTokenMaxTTL time.Duration
+	// DebugDump controls what the IsDebug request and response dumps may log
+	DebugDump DumpConfig
+	// PanicReporter is called with every recovered panic, e.g. to send it to a crash reporter
+	PanicReporter PanicReporter
+	// LogLevelRoute serves the log level for GET and changes it for PUT, when not empty.
+	// Requests must be allowed by AdminAuthenticator.
+	LogLevelRoute      string
+	AdminAuthenticator AdminAuthenticator
+	// DrainRoute reports whether the app is draining for GET and starts draining for POST, when not
+	// empty, e.g. to take an instance out of service. Requests must be allowed by AdminAuthenticator.
+	DrainRoute string
+	// ErrorCatalogueRoute serves the codes in DefaultErrorRegistry as JSON, when not empty
+	ErrorCatalogueRoute string
+	// Tracer traces every request; when nil a New Relic tracer is created from the New Relic settings
+	Tracer Tracer
 }
 
 func NewConfig() Config {
 	return Config{
-		ProjectName:       "",
-		AppName:           "",
-		EnvName:           "",
-		BuildVersion:      "",
-		RoutePrefix:       "",
-		LogLevel:          logrus.InfoLevel,
-		LogFormatter:      &logrus.JSONFormatter{},
-		IsDebug:           false,
-		NewRelicLicense:   "",
-		NewRelicEnabled:   true,
-		ErrorHandler:      DefaultErrorHandler(),
-		UseDefaultHeaders: true,
+		ProjectName:          "",
+		AppName:              "",
+		EnvName:              "",
+		BuildVersion:         "",
+		RoutePrefix:          "",
+		LogLevel:             logrus.InfoLevel,
+		LogFormatter:         &logrus.JSONFormatter{},
+		IsDebug:              false,
+		NewRelicLicense:      "",
+		NewRelicEnabled:      true,
+		ErrorHandler:         DefaultErrorHandler(),
+		UseDefaultHeaders:    true,
+		DrainTimeout:         30 * time.Second,
+		HealthCheckCacheTTL:  2 * time.Second,
+		MetricsEnabled:       false,
+		MetricsRoute:         "/metrics",
+		IgnoredErrorStatuses: []int{http.StatusNotFound},
+		DebugTokenMaxTTL:     defaultDebugTokenMaxTTL,
+		DebugDump:            DefaultDumpConfig(),
+		LogHeaders:           DefaultHeaderLogConfig(),
+		AccessLogBodies:      DefaultBodyLogConfig(),
+		LogSchema:            LogSchemaXecho,
+		AccessLogSkippers:    []func(c *Context) bool{skipHealthChecker},
 	}
 }
 
+// New creates an Xecho app from conf, panicking if the New Relic agent cannot be created.
+// Use Create to validate conf and get an error instead.
 func New(conf Config) *Xecho {
-	e, nrApp := newEcho(conf)
-	return &Xecho{NewRelicApp: nrApp, Echo: e}
+	x, err := newXecho(conf)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// Create validates conf and creates an Xecho app from it, returning an error instead of panicking
+func Create(conf Config) (*Xecho, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	return newXecho(conf)
 }
 
 func Echo(conf Config) *echo.Echo {
-	e, _ := newEcho(conf)
-	return e
+	return New(conf).Echo
 }
 
-func newEcho(conf Config) (*echo.Echo, newrelic.Application) {
+func newXecho(conf Config) (*Xecho, error) {
+	if conf.ErrorFormat != "" {
+		handler, err := ErrorHandlerForFormat(conf.ErrorFormat, conf.ProblemTypeBaseURI)
+		if err != nil {
+			return nil, err
+		}
+		conf.ErrorHandler = handler
+	}
+	if conf.ErrorMessages != nil {
+		conf.ErrorHandler = LocalisedErrorHandler(conf.ErrorMessages, conf.ErrorHandler)
+	}
 	logger := logger(conf)
 
-	newRelicApp := createNewRelicApp(conf, logger)
+	tracer := conf.Tracer
+	var newRelicApp newrelic.Application
+	if tracer == nil {
+		var err error
+		newRelicApp, err = createNewRelicApp(conf, logger)
+		if err != nil {
+			return nil, err
+		}
+		tracer = NewRelicTracer(newRelicApp)
+	}
 
 	e := echo.New()
 	e.HideBanner = true
@@ -72,32 +181,135 @@ func newEcho(conf Config) (*echo.Echo, newrelic.Application) {
 	e.Logger = &Logger{logger}
 
 	// the order of these middleware is important - context should be first, error should be after logging ones
-	e.Use(ContextMiddleware(conf.BuildVersion, logger, conf.IsDebug, newRelicApp))
-	e.Use(PanicHandlerMiddleware(conf.ErrorHandler))
+	e.Use(ContextMiddlewareWithConfig(ContextConfig{
+		BuildVersion:     conf.BuildVersion,
+		Logger:           logger,
+		IsDebug:          conf.IsDebug,
+		Tracer:           tracer,
+		DebugDump:        &conf.DebugDump,
+		LogHeaders:       &conf.LogHeaders,
+		LogSchema:        conf.LogSchema,
+		DebugSecret:      conf.DebugSecret,
+		DebugTokenMaxTTL: conf.DebugTokenMaxTTL,
+	}))
+	var metrics *Metrics
+	if conf.MetricsEnabled {
+		metrics = NewMetrics(time.Now)
+		e.Use(MetricsMiddleware(metrics))
+	}
+	e.Use(PanicHandlerMiddlewareWithConfig(PanicHandlerConfig{
+		ErrorHandler: conf.ErrorHandler,
+		Reporter:     conf.PanicReporter,
+	}))
 	if conf.UseDefaultHeaders {
 		e.Use(DefaultHeadersMiddleware())
 	}
-	e.Use(RequestLoggerMiddleware(time.Now))
+	skipRoutes := append([]string(nil), conf.AccessLogSkipRoutes...)
+	if metrics != nil {
+		skipRoutes = append(skipRoutes, conf.MetricsRoute)
+	}
+	e.Use(RequestLoggerMiddlewareWithConfig(RequestLoggerConfig{
+		Now: time.Now,
+		Skipper: AccessLogSkipper(
+			prefixRoutes(conf.RoutePrefix, skipRoutes),
+			conf.AccessLogSkipUserAgents,
+			conf.AccessLogSkippers...,
+		),
+		Sampling:      prefixSamplingRules(conf.RoutePrefix, conf.AccessLogSampling),
+		SlowThreshold: conf.AccessLogSlowThreshold,
+		Bodies:        prefixBodyLogRoutes(conf.RoutePrefix, conf.AccessLogBodies),
+		Schema:        conf.LogSchema,
+	}))
 	e.Use(DebugLoggerMiddleware(conf.IsDebug))
-	e.Use(ErrorHandlerMiddleware(conf.ErrorHandler))
+	e.Use(ErrorHandlerMiddlewareWithConfig(ErrorHandlerConfig{
+		ErrorHandler: conf.ErrorHandler,
+		ReportStatus: ErrorStatusRule(conf.IgnoredErrorStatuses, conf.IgnoreClientErrors),
+	}))
+
+	x := &Xecho{
+		NewRelicApp: newRelicApp,
+		Tracer:      tracer,
+		Echo:        e,
+		conf:        conf,
+		logger:      logger,
+		health:      newHealthChecks(conf.HealthCheckCacheTTL, time.Now),
+		logLevels:   newLogLevels(logger, conf.LogLevel, time.Now),
+	}
+
+	addHealthCheck(x)
+	if metrics != nil {
+		x.GET(conf.MetricsRoute, metrics.Handler)
+	}
+	if conf.LogLevelRoute != "" {
+		admin := x.Group(conf.LogLevelRoute, requireAdmin(conf.AdminAuthenticator))
+		admin.GET("", x.logLevels.getHandler)
+		admin.PUT("", Bind(x.logLevels.putHandler))
+	}
+	if conf.DrainRoute != "" {
+		admin := x.Group(conf.DrainRoute, requireAdmin(conf.AdminAuthenticator))
+		admin.GET("", x.drainStatusHandler)
+		admin.POST("", x.drainHandler)
+	}
+	if conf.ErrorCatalogueRoute != "" {
+		x.GET(conf.ErrorCatalogueRoute, DefaultErrorRegistry.Handler)
+	}
+
+	return x, nil
+}
 
-	addHealthCheck(conf, e)
+func addHealthCheck(x *Xecho) {
+	withBuildVersion := func(next Handler) Handler {
+		return func(c *Context) error {
+			if len(x.conf.BuildVersion) > 0 {
+				c.Response().Header().Add(headerBuildVersion, x.conf.BuildVersion)
+			}
+			return next(c)
+		}
+	}
 
-	return e, newRelicApp
+	health := x.Group("/health", withBuildVersion)
+	health.GET("", x.health.healthHandler)
+	health.GET("/live", x.health.livenessHandler)
+	health.GET("/ready", x.health.readinessHandler)
 }
 
-func addHealthCheck(conf Config, e *echo.Echo) {
-	healthRoute := "/health"
-	if conf.RoutePrefix != "" {
-		healthRoute = fmt.Sprintf("%s/health", conf.RoutePrefix)
+func prefixRoutes(prefix string, routes []string) []string {
+	prefixed := make([]string, len(routes))
+	for i, route := range routes {
+		prefixed[i] = prefixRoute(prefix, route)
 	}
+	return prefixed
+}
 
-	e.GET(healthRoute, EchoHandler(func(c *Context) error {
-		if len(conf.BuildVersion) > 0 {
-			c.Response().Header().Add(headerBuildVersion, conf.BuildVersion)
+func prefixSamplingRules(prefix string, rules []LogSamplingRule) []LogSamplingRule {
+	prefixed := make([]LogSamplingRule, len(rules))
+	for i, rule := range rules {
+		if rule.Route != "" {
+			rule.Route = prefixRoute(prefix, rule.Route)
 		}
-		return c.JSONBlob(http.StatusOK, []byte(`{"status": "ok"}`))
-	}))
+		prefixed[i] = rule
+	}
+	return prefixed
+}
+
+func prefixBodyLogRoutes(prefix string, bodies BodyLogConfig) BodyLogConfig {
+	bodies.Routes = prefixRoutes(prefix, bodies.Routes)
+	return bodies
+}
+
+func allErrorStatuses() []int {
+	statuses := []int{0, 5} // gRPC OK and NOT_FOUND, ignored by default
+	for status := http.StatusBadRequest; status <= 599; status++ {
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func prefixRoute(prefix, route string) string {
+	if prefix == "" {
+		return route
+	}
+	return fmt.Sprintf("%s%s", prefix, route)
 }
 
 func getHostName() string {
@@ -115,30 +327,25 @@ func getServiceName(projectName, appName, envName string) string {
 func logger(conf Config) *logrus.Entry {
 	logger := logrus.New()
 	logger.SetLevel(conf.LogLevel)
-	logger.SetFormatter(conf.LogFormatter)
-	entry := logger.WithFields(
-		logrus.Fields{
-			"service_name":  getServiceName(conf.ProjectName, conf.AppName, conf.EnvName),
-			"project":       conf.ProjectName,
-			"application":   conf.AppName,
-			"environment":   conf.EnvName,
-			"build_version": conf.BuildVersion,
-			"hostname":      getHostName(),
-		})
+	logger.SetFormatter(conf.LogSchema.formatter(conf.LogFormatter))
+	entry := logger.WithFields(conf.LogSchema.appFields(conf))
 	entry.Infof("XEcho app created %s(%s)", conf.AppName, conf.BuildVersion)
 	return entry
 }
 
-func createNewRelicApp(conf Config, logger *logrus.Entry) newrelic.Application {
+func createNewRelicApp(conf Config, logger *logrus.Entry) (newrelic.Application, error) {
 	nrConf := newrelic.NewConfig(getServiceName(conf.ProjectName, conf.AppName, conf.EnvName), conf.NewRelicLicense)
 	nrConf.CrossApplicationTracer.Enabled = false
 	nrConf.DistributedTracer.Enabled = true
 	nrConf.Logger = nrlogrus.Transform(logger.Logger)
 	nrConf.Enabled = conf.NewRelicEnabled
 	nrConf.Labels = map[string]string{"Env": conf.EnvName, "Project": conf.ProjectName}
+	// errors are noticed by the error handler according to the status rules in conf, whether returned
+	// or written by the handler, so that each is noticed once
+	nrConf.ErrorCollector.IgnoreStatusCodes = allErrorStatuses()
 	app, err := newrelic.NewApplication(nrConf)
 	if err != nil {
-		panic(fmt.Sprintf("Failed to register New Relic Agent, error: %s", err.Error()))
+		return nil, fmt.Errorf("failed to register New Relic agent: %s", err)
 	}
-	return app
+	return app, nil
 }
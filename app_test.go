@@ -6,6 +6,7 @@ import (
 
 	"github.com/JSainsburyPLC/xecho"
 	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestHealthCheck(t *testing.T) {
@@ -35,3 +36,23 @@ func config(routePrefix string) xecho.Config {
 	config.NewRelicEnabled = false
 	return config
 }
+
+func TestCreate(t *testing.T) {
+	x, err := xecho.Create(config(""))
+	assert.NoError(t, err)
+	assert.NotNil(t, x.Echo)
+
+	conf := config("")
+	conf.AppName = ""
+	x, err = xecho.Create(conf)
+	assert.Nil(t, x)
+	assert.EqualError(t, err, "invalid xecho config: AppName must not be empty")
+}
+
+func TestNew_PanicsWhenNewRelicRejectsConfig(t *testing.T) {
+	conf := config("")
+	conf.NewRelicEnabled = true
+	conf.NewRelicLicense = "invalid"
+
+	assert.Panics(t, func() { xecho.New(conf) })
+}
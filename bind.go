@@ -0,0 +1,292 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo"
+)
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// Bind wraps a handler taking a request of type T, which must be a struct.
+// The request is bound and validated by Context.BindRequest before h is called.
+// Bind panics if T has a parameter field of a type that can't be bound.
+func Bind[T any](h func(c *Context, req *T) error) Handler {
+	if err := checkBindable(reflect.TypeOf((*T)(nil)).Elem()); err != nil {
+		panic(err)
+	}
+	return func(c *Context) error {
+		req := new(T)
+		if err := c.BindRequest(req); err != nil {
+			return err
+		}
+		return h(c, req)
+	}
+}
+
+// BindRequest decodes the JSON body, then path parameters, query parameters and headers, into
+// the struct i points to, and validates it. Fields are bound from the `json`, `param`, `query`
+// and `header` tags and validated by the `validate` tag (see validateRequest for the rules).
+// An *Error with status 400 is returned if the request cannot be decoded, or 422 if it is invalid,
+// listing every field at fault in Violations.
+func (c *Context) BindRequest(i interface{}) error {
+	v := reflect.ValueOf(i)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("xecho: BindRequest requires a pointer to a struct, got %T", i)
+	}
+	if err := checkBindable(v.Elem().Type()); err != nil {
+		return err
+	}
+
+	if err := bindBody(c.Request(), i); err != nil {
+		return err
+	}
+	if violations := bindParams(c, v.Elem()); len(violations) > 0 {
+		return invalidRequest("Invalid request parameters", violations, nil)
+	}
+	return validateRequest(v.Elem())
+}
+
+// Bind binds the request body with echo's binder, returning an INVALID_REQUEST *Error with the
+// binder's status, 400 or 415, if it fails
+func (c *Context) Bind(i interface{}) error {
+	err := c.Context.Bind(i)
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		invalid := invalidRequest(fmt.Sprintf("%v", httpErr.Message), nil, err)
+		invalid.Status = httpErr.Code
+		return invalid
+	}
+	return err
+}
+
+func bindBody(r *http.Request, i interface{}) error {
+	if r.Body == nil {
+		return nil
+	}
+	b, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return invalidRequest("Unable to read request body", nil, err)
+	}
+	// restore the body so that it can still be read by the handler
+	r.Body = ioutil.NopCloser(bytes.NewReader(b))
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil
+	}
+
+	if !strings.HasPrefix(r.Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		err := invalidRequest("Request body must be JSON", nil, nil)
+		err.Status = http.StatusUnsupportedMediaType
+		return err
+	}
+
+	if err := json.Unmarshal(b, i); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return invalidRequest("Invalid request body", []FieldViolation{{
+				Field:    typeErr.Field,
+				Location: "body",
+				Message:  fmt.Sprintf("must be of type %s", typeErr.Type),
+			}}, err)
+		}
+		return invalidRequest("Malformed JSON body", nil, err)
+	}
+	return nil
+}
+
+type paramSource struct {
+	tag      string
+	location string
+	values   func(c *Context, name string) []string
+}
+
+var paramSources = []paramSource{
+	{tag: "param", location: "path", values: func(c *Context, name string) []string {
+		if value := c.Param(name); value != "" {
+			return []string{value}
+		}
+		return nil
+	}},
+	{tag: "query", location: "query", values: func(c *Context, name string) []string {
+		return c.QueryParams()[name]
+	}},
+	{tag: "header", location: "header", values: func(c *Context, name string) []string {
+		return c.Request().Header[http.CanonicalHeaderKey(name)]
+	}},
+}
+
+func bindParams(c *Context, v reflect.Value) []FieldViolation {
+	var violations []FieldViolation
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			violations = append(violations, bindParams(c, v.Field(i))...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		for _, source := range paramSources {
+			name := f.Tag.Get(source.tag)
+			if name == "" || name == "-" {
+				continue
+			}
+			values := source.values(c, name)
+			if len(values) == 0 {
+				continue
+			}
+			if err := setField(v.Field(i), values); err != nil {
+				violations = append(violations, FieldViolation{Field: name, Location: source.location, Message: err.Error()})
+			}
+		}
+	}
+	return violations
+}
+
+// bindableTypes caches the result of checkBindable by type, as BindRequest checks on every request
+var bindableTypes sync.Map
+
+// checkBindable returns an error if a parameter field of the struct type t can't be bound, or if a
+// `validate` tag is invalid
+func checkBindable(t reflect.Type) error {
+	if err, ok := bindableTypes.Load(t); ok {
+		return errOrNil(err)
+	}
+	err := checkParamTypes(t)
+	if err == nil {
+		err = checkValidateTags(t, "", map[reflect.Type]bool{})
+	}
+	bindableTypes.Store(t, err)
+	return err
+}
+
+func errOrNil(v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	return v.(error)
+}
+
+func checkParamTypes(t reflect.Type) error {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := checkParamTypes(f.Type); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		for _, source := range paramSources {
+			if name := f.Tag.Get(source.tag); name != "" && name != "-" && !bindableType(f.Type) {
+				return fmt.Errorf("xecho: cannot bind request parameter %s to field %s.%s of type %s",
+					name, t.Name(), f.Name, f.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// bindableType mirrors setField
+func bindableType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr && !t.Implements(textUnmarshalerType) {
+		return bindableType(t.Elem())
+	}
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func setField(v reflect.Value, values []string) error {
+	if v.Kind() == reflect.Ptr && !v.Type().Implements(textUnmarshalerType) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), values)
+	}
+	if v.Kind() == reflect.Slice {
+		s := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, value := range values {
+			if err := setValue(s.Index(i), value); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+	return setValue(v, values[0])
+}
+
+func setValue(v reflect.Value, value string) error {
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return errors.New("is invalid")
+		}
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a non-negative integer")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	default:
+		// not reached, as checkBindable rejects other types
+		return fmt.Errorf("cannot be bound to %s", v.Type())
+	}
+	return nil
+}
+
+func invalidRequest(detail string, violations []FieldViolation, reason error) *Error {
+	err := ErrInvalidRequest.WithDetail(detail).WithViolations(violations...)
+	if reason != nil {
+		return err.Wrap(reason)
+	}
+	return err
+}
@@ -0,0 +1,180 @@
+package xecho_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/JSainsburyPLC/xecho"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+type createOrderRequest struct {
+	StoreID   int      `param:"storeId"`
+	DryRun    bool     `query:"dryRun"`
+	Tags      []string `query:"tag"`
+	RequestID string   `header:"X-Request-Id" validate:"required"`
+	Item      string   `json:"item" validate:"required,max=10"`
+	Quantity  *int     `json:"quantity" validate:"min=1"`
+}
+
+func TestBind_DecodesPathQueryHeaderAndBody(t *testing.T) {
+	x := xecho.New(config(""))
+	x.POST("/stores/:storeId/orders", xecho.Bind(func(c *xecho.Context, req *createOrderRequest) error {
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"store":    req.StoreID,
+			"dryRun":   req.DryRun,
+			"tags":     req.Tags,
+			"request":  req.RequestID,
+			"item":     req.Item,
+			"quantity": *req.Quantity,
+		})
+	}))
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/stores/12/orders").
+		Query("dryRun", "true").
+		Query("tag", "a").
+		Query("tag", "b").
+		Header("X-Request-Id", "abc").
+		JSON(`{"item": "apple", "quantity": 3}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"store": 12, "dryRun": true, "tags": ["a", "b"], "request": "abc", "item": "apple", "quantity": 3}`).
+		End()
+}
+
+func TestBind_InvalidParametersAreBadRequest(t *testing.T) {
+	x := xecho.New(config(""))
+	x.POST("/stores/:storeId/orders", xecho.Bind(func(c *xecho.Context, req *createOrderRequest) error {
+		return c.NoContent(http.StatusOK)
+	}))
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/stores/abc/orders").
+		Query("dryRun", "maybe").
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{
+			"code": "INVALID_REQUEST",
+			"detail": "Invalid request parameters",
+			"violations": [
+				{"field": "storeId", "location": "path", "message": "must be an integer"},
+				{"field": "dryRun", "location": "query", "message": "must be a boolean"}
+			]
+		}`).
+		End()
+}
+
+func TestBind_MalformedBodyIsBadRequest(t *testing.T) {
+	x := xecho.New(config(""))
+	x.POST("/orders", xecho.Bind(func(c *xecho.Context, req *createOrderRequest) error {
+		return c.NoContent(http.StatusOK)
+	}))
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		JSON(`{"item": 1}`).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{
+			"code": "INVALID_REQUEST",
+			"detail": "Invalid request body",
+			"violations": [{"field": "item", "location": "body", "message": "must be of type string"}]
+		}`).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		JSON(`{"item": `).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{"code": "INVALID_REQUEST", "detail": "Malformed JSON body"}`).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		Header("Content-Type", "text/plain").
+		Body("apple").
+		Expect(t).
+		Status(http.StatusUnsupportedMediaType).
+		Body(`{"code": "INVALID_REQUEST", "detail": "Request body must be JSON"}`).
+		End()
+}
+
+func TestBind_ValidationFailureIsUnprocessable(t *testing.T) {
+	x := xecho.New(config(""))
+	x.POST("/orders", xecho.Bind(func(c *xecho.Context, req *createOrderRequest) error {
+		return c.NoContent(http.StatusOK)
+	}))
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		JSON(`{"item": "watermelons", "quantity": 0}`).
+		Expect(t).
+		Status(http.StatusUnprocessableEntity).
+		Body(`{
+			"code": "VALIDATION_FAILED",
+			"detail": "Validation failed",
+			"violations": [
+				{"field": "X-Request-Id", "location": "header", "message": "is required"},
+				{"field": "item", "location": "body", "message": "must be at most 10 characters"},
+				{"field": "quantity", "location": "body", "message": "must be at least 1"}
+			]
+		}`).
+		End()
+}
+
+func TestEchoBindErrorHasConsistentCode(t *testing.T) {
+	x := xecho.New(config(""))
+	x.POST("/orders", func(c *xecho.Context) error {
+		var req createOrderRequest
+		if err := c.Bind(&req); err != nil {
+			return err
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		JSON(`{"item": `).
+		Expect(t).
+		Status(http.StatusBadRequest).
+		Body(`{"code": "INVALID_REQUEST", "detail": "unexpected EOF"}`).
+		End()
+}
+
+func TestBind_UnbindableParameterPanicsWhenCreated(t *testing.T) {
+	type request struct {
+		Filter map[string]string `query:"filter"`
+	}
+
+	assert.PanicsWithError(t, "xecho: cannot bind request parameter filter to field request.Filter of type map[string]string", func() {
+		xecho.Bind(func(c *xecho.Context, req *request) error { return nil })
+	})
+}
+
+func TestBind_InvalidValidateTagPanicsWhenCreated(t *testing.T) {
+	type item struct {
+		SKU string `json:"sku" validate:"min=ten"`
+	}
+	type request struct {
+		Name  string `json:"name" validate:"required,sorted"`
+		Items []item `json:"items"`
+	}
+	type nestedRequest struct {
+		Items []item `json:"items"`
+	}
+
+	assert.PanicsWithError(t, `xecho: field name: unknown rule "sorted"`, func() {
+		xecho.Bind(func(c *xecho.Context, req *request) error { return nil })
+	})
+	assert.PanicsWithError(t, `xecho: field items.sku: invalid rule "min=ten"`, func() {
+		xecho.Bind(func(c *xecho.Context, req *nestedRequest) error { return nil })
+	})
+}
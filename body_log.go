@@ -0,0 +1,178 @@
+package xecho
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+// BodyLogConfig opts routes in to having their request and response bodies in the access log
+type BodyLogConfig struct {
+	// Routes are the registered route patterns whose bodies are logged
+	Routes []string
+	// MaxSize is the most bytes of a body that are logged. Larger bodies are left out and
+	// marked with body_truncated, as a partial body can't be parsed and redacted.
+	MaxSize int
+	// ContentTypes are the media types that are logged, e.g. application/json
+	ContentTypes []string
+	// RedactFields are JSON and form fields replaced with [REDACTED], matched as in DumpConfig
+	RedactFields []string
+}
+
+func DefaultBodyLogConfig() BodyLogConfig {
+	return BodyLogConfig{
+		MaxSize:      4 << 10, // 4kb
+		ContentTypes: []string{echo.MIMEApplicationJSON, echo.MIMEApplicationForm},
+		RedactFields: DefaultDumpConfig().RedactFields,
+	}
+}
+
+func (b BodyLogConfig) logsRoute(route string) bool {
+	for _, r := range b.Routes {
+		if r == route {
+			return true
+		}
+	}
+	return false
+}
+
+func (b BodyLogConfig) logsContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return containsFold(b.ContentTypes, mediaType)
+}
+
+// captureRequest reads at most MaxSize+1 bytes of the request body, leaving the body intact for
+// the handler. truncated is set if the body is larger than MaxSize.
+func (b BodyLogConfig) captureRequest(r *http.Request) (body []byte, truncated bool) {
+	if r.Body == nil || r.Body == http.NoBody || !b.logsContentType(r.Header.Get(echo.HeaderContentType)) {
+		return nil, false
+	}
+	if r.ContentLength > int64(b.MaxSize) {
+		return nil, true
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, int64(b.MaxSize)+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return nil, false
+	}
+	if len(body) > b.MaxSize {
+		return nil, true
+	}
+	return body, false
+}
+
+// fields returns the body as a field, parsed if it is JSON or a form, or marks it truncated
+func (b BodyLogConfig) fields(body []byte, truncated bool, contentType string) logrus.Fields {
+	if truncated {
+		return logrus.Fields{"body_truncated": true}
+	}
+	if len(body) == 0 {
+		return logrus.Fields{}
+	}
+
+	redactor := DumpConfig{RedactFields: b.RedactFields}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case isJSONMediaType(contentType):
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err == nil {
+			return logrus.Fields{"body": redactor.redactJSON(v, "")}
+		}
+	case mediaType == echo.MIMEApplicationForm:
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			for name := range values {
+				if redactor.isRedactedField(name, name) {
+					values[name] = []string{redacted}
+				}
+			}
+			return logrus.Fields{"body": values}
+		}
+	}
+	return logrus.Fields{"body": string(body)}
+}
+
+// bodyCaptureWriter keeps the first limit+1 bytes written to the response, until it is hijacked
+type bodyCaptureWriter struct {
+	http.ResponseWriter
+	limit    int
+	body     bytes.Buffer
+	hijacked bool
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	if remaining := w.limit + 1 - w.body.Len(); remaining > 0 && !w.hijacked {
+		if len(b) < remaining {
+			remaining = len(b)
+		}
+		w.body.Write(b[:remaining])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyCaptureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack stops capturing, as what is written to the connection can't be seen
+func (w *bodyCaptureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	w.hijacked = true
+	return h.Hijack()
+}
+
+func (w *bodyCaptureWriter) captured() (body []byte, truncated bool) {
+	if w.hijacked {
+		return nil, false
+	}
+	if w.body.Len() > w.limit {
+		return nil, true
+	}
+	return w.body.Bytes(), false
+}
+
+// capturedBodies holds the bodies of a request whose route logs them
+type capturedBodies struct {
+	conf             BodyLogConfig
+	request          []byte
+	requestTruncated bool
+	response         *bodyCaptureWriter
+}
+
+func (b *capturedBodies) addRequest(fields logrus.Fields, r *http.Request) {
+	for k, v := range b.conf.fields(b.request, b.requestTruncated, r.Header.Get(echo.HeaderContentType)) {
+		fields[k] = v
+	}
+}
+
+// addResponse adds the response body, unless its content type isn't logged
+func (b *capturedBodies) addResponse(fields logrus.Fields, res *echo.Response) {
+	contentType := res.Header().Get(echo.HeaderContentType)
+	if !b.conf.logsContentType(contentType) {
+		return
+	}
+	body, truncated := b.response.captured()
+	for k, v := range b.conf.fields(body, truncated, contentType) {
+		fields[k] = v
+	}
+}
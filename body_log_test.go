@@ -0,0 +1,118 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestLogger_LogsBodiesOfOptedInRoutes(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.RoutePrefix = "/acme"
+	conf.AccessLogBodies.Routes = []string{"/orders", "/login"}
+	conf.AccessLogBodies.MaxSize = 64
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	var handlerBody string
+	x.POST("/orders", func(c *Context) error {
+		b, _ := ioutil.ReadAll(c.Request().Body)
+		handlerBody = string(b)
+		return c.JSON(http.StatusCreated, map[string]interface{}{"id": 1, "token": "t-1"})
+	})
+	x.POST("/login", func(c *Context) error { return c.HTML(http.StatusOK, "<p>welcome</p>") })
+	x.POST("/basket", func(c *Context) error { return c.JSON(http.StatusOK, map[string]int{"items": 1}) })
+
+	type bodies struct {
+		Request struct {
+			Body          interface{} `json:"body"`
+			BodyTruncated bool        `json:"body_truncated"`
+		} `json:"request"`
+		Response struct {
+			Body          interface{} `json:"body"`
+			BodyTruncated bool        `json:"body_truncated"`
+		} `json:"response"`
+	}
+	logged := func(path string, contentType string, body string) bodies {
+		buffer.Reset()
+		apitest.New().Handler(x.Echo).Post(path).Header("Content-Type", contentType).Body(body).Expect(t).End()
+		var line bodies
+		assert.NoError(t, json.Unmarshal(buffer.Bytes(), &line))
+		return line
+	}
+
+	line := logged("/acme/orders", "application/json", `{"sku":"123","card":{"cvv":"999"}}`)
+	assert.Equal(t, map[string]interface{}{"sku": "123", "card": map[string]interface{}{"cvv": "[REDACTED]"}}, line.Request.Body)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "token": "[REDACTED]"}, line.Response.Body)
+	assert.Equal(t, `{"sku":"123","card":{"cvv":"999"}}`, handlerBody, "the handler should read the whole body")
+
+	large := `{"sku":"` + strings.Repeat("1", 100) + `"}`
+	line = logged("/acme/orders", "application/json", large)
+	assert.Nil(t, line.Request.Body)
+	assert.True(t, line.Request.BodyTruncated)
+	assert.Equal(t, large, handlerBody)
+
+	line = logged("/acme/login", "application/x-www-form-urlencoded", "user=ann&password=hunter2")
+	assert.Equal(t, map[string]interface{}{"user": []interface{}{"ann"}, "password": []interface{}{"[REDACTED]"}},
+		line.Request.Body)
+	assert.Nil(t, line.Response.Body, "HTML responses should not be logged")
+
+	line = logged("/acme/login", "text/plain", "hello")
+	assert.Nil(t, line.Request.Body, "text bodies should not be logged")
+
+	line = logged("/acme/basket", "application/json", `{"sku":"123"}`)
+	assert.Nil(t, line.Request.Body, "routes should be opted in")
+	assert.Nil(t, line.Response.Body)
+}
+
+func TestRequestLogger_BodyLoggedRoutesCanFlushAndHijack(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.AccessLogBodies.Routes = []string{"/stream", "/upgrade"}
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/stream", func(c *Context) error {
+		c.Response().Header().Set("Content-Type", "application/json")
+		c.Response().WriteHeader(http.StatusOK)
+		c.Response().Write([]byte(`{"part":1}`))
+		c.Response().Flush()
+		return nil
+	})
+	x.GET("/upgrade", func(c *Context) error {
+		c.Response().Header().Set("Content-Type", "application/json")
+		conn, rw, err := c.Response().Hijack()
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 200 OK\r\nContent-Type: application/json\r\nContent-Length: 12\r\n\r\n{\"raw\":true}")
+		return rw.Flush()
+	})
+
+	rec := httptest.NewRecorder()
+	x.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	assert.True(t, rec.Flushed)
+	assert.Contains(t, buffer.String(), `"body":{"part":1}`)
+
+	buffer.Reset()
+	served := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		x.Echo.ServeHTTP(w, r)
+		close(served)
+	}))
+	defer server.Close()
+	res, err := http.Get(server.URL + "/upgrade")
+	assert.NoError(t, err)
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	assert.Equal(t, `{"raw":true}`, string(b))
+	<-served
+	assert.NotContains(t, buffer.String(), `"body"`)
+}
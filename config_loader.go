@@ -0,0 +1,296 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"gopkg.in/yaml.v2"
+)
+
+// secretFileSuffix marks a key whose value is the path of a file holding the real value,
+// e.g. NEW_RELIC_LICENSE_FILE=/var/run/secrets/new-relic/license
+const secretFileSuffix = "_file"
+
+// configKeys lists every key understood by LoadConfig and how it is applied to a Config
+var configKeys = map[string]func(conf *Config, value string) error{
+	"project_name":  func(conf *Config, value string) error { conf.ProjectName = value; return nil },
+	"app_name":      func(conf *Config, value string) error { conf.AppName = value; return nil },
+	"env_name":      func(conf *Config, value string) error { conf.EnvName = value; return nil },
+	"build_version": func(conf *Config, value string) error { conf.BuildVersion = value; return nil },
+	"route_prefix":  func(conf *Config, value string) error { conf.RoutePrefix = value; return nil },
+	"log_level": func(conf *Config, value string) error {
+		level, err := logrus.ParseLevel(value)
+		conf.LogLevel = level
+		return err
+	},
+	"log_format": func(conf *Config, value string) error {
+		switch strings.ToLower(value) {
+		case "json":
+			conf.LogFormatter = &logrus.JSONFormatter{}
+		case "text":
+			conf.LogFormatter = &logrus.TextFormatter{}
+		default:
+			return fmt.Errorf("unknown log format %q", value)
+		}
+		return nil
+	},
+	"tracer": func(conf *Config, value string) error {
+		switch strings.ToLower(value) {
+		case "newrelic":
+			conf.Tracer = nil
+		case "noop":
+			conf.Tracer = NoopTracer()
+		case "otel-stdout":
+			exporter, err := stdouttrace.New()
+			if err != nil {
+				return err
+			}
+			conf.Tracer = NewOTelTracer(sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter)))
+		default:
+			return fmt.Errorf("unknown tracer %q", value)
+		}
+		return nil
+	},
+	"error_format": func(conf *Config, value string) error {
+		if _, err := ErrorHandlerForFormat(value, ""); err != nil {
+			return err
+		}
+		conf.ErrorFormat = strings.ToLower(value)
+		return nil
+	},
+	"problem_type_base_uri": func(conf *Config, value string) error { conf.ProblemTypeBaseURI = value; return nil },
+	"log_schema": func(conf *Config, value string) error {
+		schema := LogSchema(strings.ToLower(value))
+		if !schema.valid() {
+			return fmt.Errorf("unknown log schema %q", value)
+		}
+		conf.LogSchema = schema
+		return nil
+	},
+	"log_level_route": func(conf *Config, value string) error { conf.LogLevelRoute = value; return nil },
+	"drain_route":     func(conf *Config, value string) error { conf.DrainRoute = value; return nil },
+	// admin_tokens is a list of user:token pairs, best given as a secret file with ADMIN_TOKENS_FILE
+	"admin_tokens": func(conf *Config, value string) error {
+		tokens := map[string]string{}
+		for i, pair := range strings.Split(value, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			user, token, ok := strings.Cut(pair, ":")
+			if !ok || user == "" || token == "" {
+				// the pair isn't quoted, as it may be a token
+				return fmt.Errorf("admin token %d must be given as user:token", i+1)
+			}
+			tokens[user] = token
+		}
+		conf.AdminAuthenticator = AdminTokenAuthenticator(tokens)
+		return nil
+	},
+	"error_catalogue_route": func(conf *Config, value string) error { conf.ErrorCatalogueRoute = value; return nil },
+	"ignored_error_statuses": func(conf *Config, value string) error {
+		conf.IgnoredErrorStatuses = nil
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			status, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			conf.IgnoredErrorStatuses = append(conf.IgnoredErrorStatuses, status)
+		}
+		return nil
+	},
+	"ignore_client_errors": boolConfigKey(func(conf *Config, b bool) { conf.IgnoreClientErrors = b }),
+	"debug_redact_headers": stringListConfigKey(func(conf *Config, l []string) { conf.DebugDump.RedactHeaders = l }),
+	"debug_redact_fields":  stringListConfigKey(func(conf *Config, l []string) { conf.DebugDump.RedactFields = l }),
+	"debug_max_body_size": func(conf *Config, value string) error {
+		size, err := strconv.Atoi(value)
+		conf.DebugDump.MaxBodySize = size
+		return err
+	},
+	"access_log_skip_routes": stringListConfigKey(func(conf *Config, l []string) { conf.AccessLogSkipRoutes = l }),
+	"access_log_skip_user_agents": stringListConfigKey(func(conf *Config, l []string) {
+		conf.AccessLogSkipUserAgents = l
+	}),
+	"access_log_slow_threshold": durationConfigKey(func(conf *Config, d time.Duration) {
+		conf.AccessLogSlowThreshold = d
+	}),
+	"access_log_body_routes": stringListConfigKey(func(conf *Config, l []string) { conf.AccessLogBodies.Routes = l }),
+	"access_log_body_max_size": func(conf *Config, value string) error {
+		size, err := strconv.Atoi(value)
+		conf.AccessLogBodies.MaxSize = size
+		return err
+	},
+	"access_log_body_content_types": stringListConfigKey(func(conf *Config, l []string) {
+		conf.AccessLogBodies.ContentTypes = l
+	}),
+	"access_log_body_redact_fields": stringListConfigKey(func(conf *Config, l []string) {
+		conf.AccessLogBodies.RedactFields = l
+	}),
+	"log_request_headers": stringListConfigKey(func(conf *Config, l []string) { conf.LogHeaders.Request = l }),
+	"log_deny_headers":    stringListConfigKey(func(conf *Config, l []string) { conf.LogHeaders.Deny = l }),
+	"log_redact_headers":  stringListConfigKey(func(conf *Config, l []string) { conf.LogHeaders.Redact = l }),
+	"log_response_headers": stringListConfigKey(func(conf *Config, l []string) {
+		conf.LogHeaders.Response = l
+	}),
+	"debug_secret":        func(conf *Config, value string) error { conf.DebugSecret = value; return nil },
+	"debug":               boolConfigKey(func(conf *Config, b bool) { conf.IsDebug = b }),
+	"new_relic_license":   func(conf *Config, value string) error { conf.NewRelicLicense = value; return nil },
+	"new_relic_enabled":   boolConfigKey(func(conf *Config, b bool) { conf.NewRelicEnabled = b }),
+	"use_default_headers": boolConfigKey(func(conf *Config, b bool) { conf.UseDefaultHeaders = b }),
+	"drain_timeout":       durationConfigKey(func(conf *Config, d time.Duration) { conf.DrainTimeout = d }),
+	"drain_delay":         durationConfigKey(func(conf *Config, d time.Duration) { conf.DrainDelay = d }),
+	"metrics_enabled":     boolConfigKey(func(conf *Config, b bool) { conf.MetricsEnabled = b }),
+	"metrics_route":       func(conf *Config, value string) error { conf.MetricsRoute = value; return nil },
+	"health_check_cache_ttl": durationConfigKey(func(conf *Config, d time.Duration) {
+		conf.HealthCheckCacheTTL = d
+	}),
+	"debug_token_max_ttl": durationConfigKey(func(conf *Config, d time.Duration) {
+		conf.DebugTokenMaxTTL = d
+	}),
+}
+
+func boolConfigKey(set func(conf *Config, b bool)) func(conf *Config, value string) error {
+	return func(conf *Config, value string) error {
+		b, err := strconv.ParseBool(value)
+		set(conf, b)
+		return err
+	}
+}
+
+// stringListConfigKey parses a comma separated list
+func stringListConfigKey(set func(conf *Config, l []string)) func(conf *Config, value string) error {
+	return func(conf *Config, value string) error {
+		var l []string
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				l = append(l, s)
+			}
+		}
+		set(conf, l)
+		return nil
+	}
+}
+
+func durationConfigKey(set func(conf *Config, d time.Duration)) func(conf *Config, value string) error {
+	return func(conf *Config, value string) error {
+		d, err := time.ParseDuration(value)
+		set(conf, d)
+		return err
+	}
+}
+
+// LoadConfig builds a Config in layers: the defaults from NewConfig, then the values in the
+// YAML or JSON file at path (skipped if path is empty), then environment variables named
+// envPrefix_KEY, e.g. with prefix "ACME" the key log_level is read from ACME_LOG_LEVEL.
+// Any key can instead be given as KEY_FILE, naming a file whose trimmed contents are the value,
+// which is how Kubernetes secrets are mounted.
+func LoadConfig(path string, envPrefix string) (Config, error) {
+	conf := NewConfig()
+
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return conf, err
+		}
+		if err := applyConfigValues(&conf, values, "config file "+path); err != nil {
+			return conf, err
+		}
+	}
+
+	if err := applyConfigValues(&conf, readConfigEnv(envPrefix), "environment"); err != nil {
+		return conf, err
+	}
+
+	return conf, nil
+}
+
+func readConfigFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		// numbers are kept as written, as float64 would format 1048576 as 1.048576e+06
+		decoder := json.NewDecoder(bytes.NewReader(b))
+		decoder.UseNumber()
+		err = decoder.Decode(&raw)
+	} else {
+		err = yaml.Unmarshal(b, &raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %s", path, err)
+	}
+
+	values := map[string]string{}
+	for key, value := range raw {
+		switch value.(type) {
+		case map[interface{}]interface{}, map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("config file %s: key %s must be a scalar value", path, key)
+		case nil:
+			continue
+		}
+		values[strings.ToLower(key)] = fmt.Sprintf("%v", value)
+	}
+	return values, nil
+}
+
+func readConfigEnv(prefix string) map[string]string {
+	if prefix != "" {
+		prefix = strings.ToUpper(prefix) + "_"
+	}
+
+	values := map[string]string{}
+	for key := range configKeys {
+		for _, k := range []string{key, key + secretFileSuffix} {
+			if value, ok := os.LookupEnv(prefix + strings.ToUpper(k)); ok {
+				values[k] = value
+			}
+		}
+	}
+	return values
+}
+
+func applyConfigValues(conf *Config, values map[string]string, source string) error {
+	// sorted so that KEY_FILE is always applied after, and wins over, KEY
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := values[key]
+		if strings.HasSuffix(key, secretFileSuffix) {
+			secret, err := ioutil.ReadFile(value)
+			if err != nil {
+				return fmt.Errorf("%s: failed to read %s: %s", source, key, err)
+			}
+			key = strings.TrimSuffix(key, secretFileSuffix)
+			value = strings.TrimSpace(string(secret))
+		}
+
+		apply, ok := configKeys[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown key %s", source, key)
+		}
+		if err := apply(conf, value); err != nil {
+			return fmt.Errorf("%s: invalid value for %s: %s", source, key, err)
+		}
+	}
+	return nil
+}
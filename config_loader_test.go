@@ -0,0 +1,143 @@
+package xecho
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfig_Defaults(t *testing.T) {
+	conf, err := LoadConfig("", "XECHO_TEST")
+
+	assert.NoError(t, err)
+	assert.Equal(t, logrus.InfoLevel, conf.LogLevel)
+	assert.True(t, conf.NewRelicEnabled)
+	assert.True(t, conf.UseDefaultHeaders)
+	assert.NotNil(t, conf.ErrorHandler)
+}
+
+func TestLoadConfig_YAMLFileOverlaidWithEnv(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "config.yaml", `
+project_name: acme
+app_name: login
+env_name: dev
+log_level: warn
+log_format: text
+new_relic_enabled: false
+drain_timeout: 5s
+ignored_error_statuses: 404, 409
+`)
+	setEnv(t, "XECHO_TEST_ENV_NAME", "prod")
+	setEnv(t, "XECHO_TEST_DEBUG", "true")
+	defer os.Unsetenv("XECHO_TEST_ENV_NAME")
+	defer os.Unsetenv("XECHO_TEST_DEBUG")
+
+	conf, err := LoadConfig(path, "xecho_test")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "acme", conf.ProjectName)
+	assert.Equal(t, "login", conf.AppName)
+	assert.Equal(t, "prod", conf.EnvName)
+	assert.Equal(t, logrus.WarnLevel, conf.LogLevel)
+	assert.IsType(t, &logrus.TextFormatter{}, conf.LogFormatter)
+	assert.False(t, conf.NewRelicEnabled)
+	assert.True(t, conf.IsDebug)
+	assert.Equal(t, 5*time.Second, conf.DrainTimeout)
+	assert.Equal(t, []int{404, 409}, conf.IgnoredErrorStatuses)
+}
+
+func TestLoadConfig_JSONFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "config.json", `{"app_name": "login", "use_default_headers": false}`)
+
+	conf, err := LoadConfig(path, "XECHO_TEST")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "login", conf.AppName)
+	assert.False(t, conf.UseDefaultHeaders)
+}
+
+func TestLoadConfig_ErrorFormat(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "config.yaml", `
+error_format: Problem
+problem_type_base_uri: https://errors.example.com/
+`)
+
+	conf, err := LoadConfig(path, "XECHO_TEST")
+
+	assert.NoError(t, err)
+	assert.Equal(t, ErrorFormatProblem, conf.ErrorFormat)
+	assert.Equal(t, "https://errors.example.com/", conf.ProblemTypeBaseURI)
+}
+
+func TestLoadConfig_JSONFileLargeIntegers(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := writeFile(t, dir, "config.json", `{"debug_max_body_size": 1048576, "access_log_body_max_size": 2097152}`)
+
+	conf, err := LoadConfig(path, "XECHO_TEST")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1048576, conf.DebugDump.MaxBodySize)
+	assert.Equal(t, 2097152, conf.AccessLogBodies.MaxSize)
+}
+
+func TestLoadConfig_SecretFromMountedFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	secret := writeFile(t, dir, "license", "1111111111111111111111111111111111111111\n")
+	setEnv(t, "XECHO_TEST_NEW_RELIC_LICENSE", "overridden-by-file")
+	setEnv(t, "XECHO_TEST_NEW_RELIC_LICENSE_FILE", secret)
+	defer os.Unsetenv("XECHO_TEST_NEW_RELIC_LICENSE")
+	defer os.Unsetenv("XECHO_TEST_NEW_RELIC_LICENSE_FILE")
+
+	conf, err := LoadConfig("", "XECHO_TEST")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "1111111111111111111111111111111111111111", conf.NewRelicLicense)
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	_, err := LoadConfig(filepath.Join(dir, "missing.yaml"), "XECHO_TEST")
+	assert.Error(t, err)
+
+	_, err = LoadConfig(writeFile(t, dir, "unknown.yaml", "colour: blue"), "XECHO_TEST")
+	assert.EqualError(t, err, "config file "+filepath.Join(dir, "unknown.yaml")+": unknown key colour")
+
+	_, err = LoadConfig(writeFile(t, dir, "nested.yaml", "app_name:\n  name: login"), "XECHO_TEST")
+	assert.Error(t, err)
+
+	setEnv(t, "XECHO_TEST_LOG_LEVEL", "loud")
+	defer os.Unsetenv("XECHO_TEST_LOG_LEVEL")
+	_, err = LoadConfig("", "XECHO_TEST")
+	assert.EqualError(t, err, `environment: invalid value for log_level: not a valid logrus Level: "loud"`)
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "xecho")
+	assert.NoError(t, err)
+	return dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func setEnv(t *testing.T, key, value string) {
+	assert.NoError(t, os.Setenv(key, value))
+}
@@ -0,0 +1,112 @@
+package xecho
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const newRelicLicenseLength = 40
+
+// ConfigError lists every problem found when validating a Config
+type ConfigError struct {
+	Problems []ConfigProblem
+}
+
+// ConfigProblem describes why the value of a single Config field is invalid
+type ConfigProblem struct {
+	Field   string
+	Message string
+}
+
+func (err *ConfigError) Error() string {
+	problems := make([]string, 0, len(err.Problems))
+	for _, p := range err.Problems {
+		problems = append(problems, fmt.Sprintf("%s %s", p.Field, p.Message))
+	}
+	return fmt.Sprintf("invalid xecho config: %s", strings.Join(problems, "; "))
+}
+
+func (err *ConfigError) add(field, message string) {
+	err.Problems = append(err.Problems, ConfigProblem{Field: field, Message: message})
+}
+
+// Validate checks conf for values that would produce a broken app, such as a service name
+// of "--" or a New Relic agent that refuses to start. It returns a *ConfigError describing
+// every problem found, or nil if conf is valid.
+func (conf Config) Validate() error {
+	err := &ConfigError{}
+
+	if conf.ProjectName == "" {
+		err.add("ProjectName", "must not be empty")
+	}
+	if conf.AppName == "" {
+		err.add("AppName", "must not be empty")
+	}
+	if conf.EnvName == "" {
+		err.add("EnvName", "must not be empty")
+	}
+	if conf.LogFormatter == nil {
+		err.add("LogFormatter", "must not be nil")
+	}
+	if conf.ErrorFormat != "" {
+		if _, formatErr := ErrorHandlerForFormat(conf.ErrorFormat, ""); formatErr != nil {
+			err.add("ErrorFormat", fmt.Sprintf("must be one of %v", errorFormats))
+		}
+	} else if conf.ErrorHandler == nil {
+		err.add("ErrorHandler", "must not be nil")
+	}
+	if conf.ProblemTypeBaseURI != "" && !strings.EqualFold(conf.ErrorFormat, ErrorFormatProblem) {
+		err.add("ProblemTypeBaseURI", "must be empty unless ErrorFormat is problem")
+	}
+	if conf.Tracer == nil && conf.NewRelicEnabled && len(conf.NewRelicLicense) != newRelicLicenseLength {
+		err.add("NewRelicLicense", fmt.Sprintf("must be %d characters when New Relic is enabled", newRelicLicenseLength))
+	}
+	if conf.DrainTimeout < 0 {
+		err.add("DrainTimeout", "must not be negative")
+	}
+	if conf.DrainDelay < 0 {
+		err.add("DrainDelay", "must not be negative")
+	}
+	for i, rule := range conf.AccessLogSampling {
+		if rule.Rate < 0 || rule.Rate > 1 {
+			err.add(fmt.Sprintf("AccessLogSampling[%d].Rate", i), "must be between 0 and 1")
+		}
+		if strings.EqualFold(rule.StatusClass, "4xx") || strings.EqualFold(rule.StatusClass, "5xx") {
+			err.add(fmt.Sprintf("AccessLogSampling[%d].StatusClass", i), "must not be 4xx or 5xx, which are always logged")
+		}
+	}
+	if conf.DebugSecret != "" && len(conf.DebugSecret) < minDebugSecretLength {
+		err.add("DebugSecret", fmt.Sprintf("must be at least %d characters", minDebugSecretLength))
+	}
+	if conf.DebugSecret != "" && conf.DebugTokenMaxTTL <= 0 {
+		err.add("DebugTokenMaxTTL", "must be positive when DebugSecret is set")
+	}
+	if conf.LogLevelRoute != "" && conf.AdminAuthenticator == nil {
+		err.add("AdminAuthenticator", "must be set when LogLevelRoute is set")
+	} else if conf.DrainRoute != "" && conf.AdminAuthenticator == nil {
+		err.add("AdminAuthenticator", "must be set when DrainRoute is set")
+	}
+	if conf.LogSchema != "" && !conf.LogSchema.valid() {
+		err.add("LogSchema", fmt.Sprintf("must be one of %v", logSchemas))
+	}
+	if conf.LogSchema == LogSchemaCombined && len(conf.AccessLogBodies.Routes) > 0 {
+		err.add("AccessLogBodies.Routes", "must be empty with the combined log schema, which can't log bodies")
+	}
+	if conf.LogSchema == LogSchemaCombined && len(conf.AccessLogSampling) > 0 {
+		err.add("AccessLogSampling", "must be empty with the combined log schema, which can't log the sample rate")
+	}
+	if len(conf.AccessLogBodies.Routes) > 0 && conf.AccessLogBodies.MaxSize <= 0 {
+		err.add("AccessLogBodies.MaxSize", "must be positive when body logging routes are set")
+	}
+	for _, status := range conf.IgnoredErrorStatuses {
+		if status < http.StatusBadRequest || status > 599 {
+			err.add("IgnoredErrorStatuses", fmt.Sprintf("must only contain 4xx and 5xx statuses, not %d", status))
+		}
+	}
+
+	if len(err.Problems) > 0 {
+		return err
+	}
+	return nil
+}
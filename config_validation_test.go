@@ -0,0 +1,72 @@
+package xecho
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	conf := NewConfig()
+	conf.ProjectName = "acme"
+	conf.AppName = "login"
+	conf.EnvName = "dev"
+	conf.NewRelicLicense = "1111111111111111111111111111111111111111"
+
+	assert.NoError(t, conf.Validate())
+}
+
+func TestConfig_ValidateReportsEveryProblem(t *testing.T) {
+	conf := NewConfig()
+	conf.LogFormatter = nil
+	conf.NewRelicLicense = "too-short"
+	conf.DrainTimeout = -time.Second
+	conf.AccessLogSampling = []LogSamplingRule{{StatusClass: "2xx", Rate: 0.1}, {StatusClass: "4XX", Rate: 0.5}}
+	conf.IgnoredErrorStatuses = []int{404, 200}
+	conf.AccessLogBodies = BodyLogConfig{Routes: []string{"/orders"}}
+	conf.LogLevelRoute = "/admin/log-level"
+	conf.DebugSecret = "short"
+	conf.DebugTokenMaxTTL = 0
+
+	err := conf.Validate()
+
+	configErr, ok := err.(*ConfigError)
+	assert.True(t, ok)
+	assert.Equal(t, []ConfigProblem{
+		{Field: "ProjectName", Message: "must not be empty"},
+		{Field: "AppName", Message: "must not be empty"},
+		{Field: "EnvName", Message: "must not be empty"},
+		{Field: "LogFormatter", Message: "must not be nil"},
+		{Field: "NewRelicLicense", Message: "must be 40 characters when New Relic is enabled"},
+		{Field: "DrainTimeout", Message: "must not be negative"},
+		{Field: "AccessLogSampling[1].StatusClass", Message: "must not be 4xx or 5xx, which are always logged"},
+		{Field: "DebugSecret", Message: "must be at least 32 characters"},
+		{Field: "DebugTokenMaxTTL", Message: "must be positive when DebugSecret is set"},
+		{Field: "AdminAuthenticator", Message: "must be set when LogLevelRoute is set"},
+		{Field: "AccessLogBodies.MaxSize", Message: "must be positive when body logging routes are set"},
+		{Field: "IgnoredErrorStatuses", Message: "must only contain 4xx and 5xx statuses, not 200"},
+	}, configErr.Problems)
+	assert.Contains(t, err.Error(), "invalid xecho config: ProjectName must not be empty; AppName must not be empty")
+}
+
+func TestConfig_ValidateLicenseNotRequiredWhenNewRelicDisabled(t *testing.T) {
+	conf := NewConfig()
+	conf.ProjectName = "acme"
+	conf.AppName = "login"
+	conf.EnvName = "dev"
+	conf.NewRelicEnabled = false
+
+	assert.NoError(t, conf.Validate())
+}
+
+func TestConfig_ValidateErrorFormat(t *testing.T) {
+	conf := testConfig()
+	conf.ErrorFormat = "xml"
+	conf.ProblemTypeBaseURI = "https://errors.example.com/"
+
+	err := conf.Validate()
+
+	assert.EqualError(t, err, "invalid xecho config: ErrorFormat must be one of [default problem jsonapi]; "+
+		"ProblemTypeBaseURI must be empty unless ErrorFormat is problem")
+}
@@ -1,8 +1,10 @@
 package xecho
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/labstack/echo"
@@ -16,9 +18,38 @@ type Context struct {
 	echo.Context
 	CorrelationID string
 	HttpClient    *http.Client
-	NewRelicApp   newrelic.Application
-	NewRelicTx    newrelic.Transaction
-	logger        *Logger
+	Tracer        Tracer
+	Transaction   Transaction
+	// NewRelicApp is only set when tracing with New Relic. Deprecated: use Tracer.
+	NewRelicApp newrelic.Application
+	// NewRelicTx is only set when tracing with New Relic. Deprecated: use Transaction.
+	NewRelicTx newrelic.Transaction
+	logger     *Logger
+	metrics    *Metrics
+	dump       *DumpConfig
+	logHeaders *HeaderLogConfig
+	logSchema  LogSchema
+	debug      bool
+}
+
+type ContextConfig struct {
+	BuildVersion string
+	Logger       *logrus.Entry
+	IsDebug      bool
+	Tracer       Tracer
+	// DebugDump controls the debug request and response dumps; DefaultDumpConfig is used when nil
+	DebugDump *DumpConfig
+	// LogHeaders selects the headers that are logged; DefaultHeaderLogConfig is used when nil
+	LogHeaders *HeaderLogConfig
+	// LogSchema selects the field names of the request-scoped logger, LogSchemaXecho when empty
+	LogSchema LogSchema
+	// DebugSecret, when set, lets a request turn on debug mode for itself with an X-Debug-Token
+	// header made by NewDebugToken
+	DebugSecret string
+	// DebugTokenMaxTTL is the furthest ahead a debug token may expire, an hour when zero
+	DebugTokenMaxTTL time.Duration
+	// Now defaults to time.Now
+	Now TimeProvider
 }
 
 type Handler func(c *Context) error
@@ -37,13 +68,43 @@ func (c *Context) Logger() echo.Logger {
 	return c.logger
 }
 
-func (c *Context) AddNewRelicAttribute(key string, val interface{}) {
-	if c.NewRelicTx == nil {
+// AddTraceAttribute adds a custom attribute to the request's transaction
+func (c *Context) AddTraceAttribute(key string, val interface{}) {
+	if c.Transaction == nil {
 		return
 	}
-	if err := c.NewRelicTx.AddAttribute(key, val); err != nil {
-		c.Logger().Errorf("failed to add attr '%s' to new relic tx: %+v", key, err)
+	if err := c.Transaction.AddAttribute(key, val); err != nil {
+		c.Logger().Errorf("failed to add attr '%s' to tx: %+v", key, err)
+	}
+}
+
+// IsDebug reports whether the request is in debug mode, either by Config.IsDebug or by a valid
+// X-Debug-Token header, when requests and responses are dumped and debug logs are written
+func (c *Context) IsDebug() bool {
+	return c.debug
+}
+
+func (c *Context) dumpConfig() DumpConfig {
+	if c.dump == nil {
+		return DefaultDumpConfig()
 	}
+	return *c.dump
+}
+
+func (c *Context) headerLogConfig() HeaderLogConfig {
+	return headerLogConfig(c.logHeaders)
+}
+
+func headerLogConfig(h *HeaderLogConfig) HeaderLogConfig {
+	if h == nil {
+		return DefaultHeaderLogConfig()
+	}
+	return *h
+}
+
+// Deprecated: use AddTraceAttribute
+func (c *Context) AddNewRelicAttribute(key string, val interface{}) {
+	c.AddTraceAttribute(key, val)
 }
 
 func ContextMiddleware(
@@ -52,20 +113,46 @@ func ContextMiddleware(
 	isDebug bool,
 	newRelicApp newrelic.Application,
 ) echo.MiddlewareFunc {
+	return ContextMiddlewareWithConfig(ContextConfig{
+		BuildVersion: buildVersion,
+		Logger:       logger,
+		IsDebug:      isDebug,
+		Tracer:       NewRelicTracer(newRelicApp),
+	})
+}
+
+func ContextMiddlewareWithConfig(conf ContextConfig) echo.MiddlewareFunc {
 	return func(h echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			correlationID := getCorrelationID(c.Request())
 			ip := c.RealIP()
+			now := conf.Now
+			if now == nil {
+				now = time.Now
+			}
+			maxTTL := conf.DebugTokenMaxTTL
+			if maxTTL == 0 {
+				maxTTL = defaultDebugTokenMaxTTL
+			}
+			requestConf := conf
+			baseLogger := conf.Logger
+			token := c.Request().Header.Get(debugTokenHeaderName)
+			if !conf.IsDebug && validDebugToken(conf.DebugSecret, token, now(), maxTTL) {
+				requestConf.IsDebug = true
+				baseLogger = debugLogger(conf.Logger)
+			}
 			logger := requestScopeLogger(
-				logger,
+				baseLogger,
 				c.Request(),
 				c.Path(),
 				ip,
 				correlationID,
+				headerLogConfig(conf.LogHeaders),
+				conf.LogSchema,
 			)
 
-			cc := NewContext(c, newRelicApp, logger, correlationID, isDebug, buildVersion)
-			defer cc.NewRelicTx.End()
+			cc := newContext(c, requestConf, logger, correlationID)
+			defer cc.Transaction.End()
 
 			return h(cc)
 		}
@@ -80,29 +167,60 @@ func NewContext(
 	isDebug bool,
 	buildVersion string,
 ) *Context {
-	newRelicTx := newRelicApp.StartTransaction(
-		echoCtx.Request().URL.Path,
+	conf := ContextConfig{
+		BuildVersion: buildVersion,
+		IsDebug:      isDebug,
+		Tracer:       NewRelicTracer(newRelicApp),
+	}
+	return newContext(echoCtx, conf, logger, correlationID)
+}
+
+func newContext(
+	echoCtx echo.Context,
+	conf ContextConfig,
+	logger *Logger,
+	correlationID string,
+) *Context {
+	name := echoCtx.Request().URL.Path
+	if rt, ok := conf.Tracer.(routeTracer); ok && rt.namesByRoute() && echoCtx.Path() != "" {
+		name = echoCtx.Path()
+	}
+	tx := conf.Tracer.StartTransaction(
+		name,
 		echoCtx.Response().Writer,
 		echoCtx.Request(),
 	)
-	// new relic tx wraps response writer
-	echoCtx.Response().Writer = newRelicTx
+	echoCtx.Response().Writer = tx.ResponseWriter()
+	if ctxTx, ok := tx.(interface{ Context() context.Context }); ok {
+		echoCtx.SetRequest(echoCtx.Request().WithContext(ctxTx.Context()))
+	}
+	if fieldsTx, ok := tx.(TransactionLogFields); ok {
+		logger = &Logger{logger.WithFields(conf.LogSchema.traceFields(fieldsTx.LogFields()))}
+	}
 
 	customCtx := &Context{
 		Context:       echoCtx,
 		CorrelationID: correlationID,
-		NewRelicApp:   newRelicApp,
-		NewRelicTx:    newRelicTx,
+		Tracer:        conf.Tracer,
+		Transaction:   tx,
 		logger:        logger,
+		dump:          conf.DebugDump,
+		logHeaders:    conf.LogHeaders,
+		logSchema:     conf.LogSchema,
+		debug:         conf.IsDebug,
+	}
+	if nrTracer, ok := conf.Tracer.(*newRelicTracer); ok {
+		customCtx.NewRelicApp = nrTracer.app
+		customCtx.NewRelicTx = tx.(*newRelicTransaction).txn
 	}
 
-	customCtx.HttpClient = NewHttpClient(customCtx, isDebug)
+	customCtx.HttpClient = NewHttpClient(customCtx, conf.IsDebug)
 
 	// TODO: build version attribute (and in logs)
-	customCtx.AddNewRelicAttribute("route", echoCtx.Path())
-	customCtx.AddNewRelicAttribute("correlationID", correlationID)
-	customCtx.AddNewRelicAttribute("ip", echoCtx.RealIP())
-	customCtx.AddNewRelicAttribute("buildVersion", buildVersion)
+	customCtx.AddTraceAttribute("route", echoCtx.Path())
+	customCtx.AddTraceAttribute("correlationID", correlationID)
+	customCtx.AddTraceAttribute("ip", echoCtx.RealIP())
+	customCtx.AddTraceAttribute("buildVersion", conf.BuildVersion)
 
 	return customCtx
 }
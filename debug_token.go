@@ -0,0 +1,128 @@
+package xecho
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// debugTokenHeaderName carries a token made by NewDebugToken, turning on debug mode for one request
+const debugTokenHeaderName = "X-Debug-Token"
+
+// minDebugSecretLength is the shortest Config.DebugSecret allowed, as it signs debug tokens
+const minDebugSecretLength = 32
+
+// defaultDebugTokenMaxTTL is how far ahead a debug token may expire unless configured otherwise
+const defaultDebugTokenMaxTTL = time.Hour
+
+// NewDebugToken returns a token for the X-Debug-Token header that turns on debug mode for the
+// requests it is sent with, until expiresAt, which must be no more than Config.DebugTokenMaxTTL
+// ahead. The token is the expiry as a unix time, a dot, then
+// the hex HMAC-SHA256 of the expiry signed with secret, which must match Config.DebugSecret.
+func NewDebugToken(secret string, expiresAt time.Time) string {
+	expiry := strconv.FormatInt(expiresAt.Unix(), 10)
+	return expiry + "." + hex.EncodeToString(signDebugToken(secret, expiry))
+}
+
+func signDebugToken(secret string, expiry string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(expiry))
+	return mac.Sum(nil)
+}
+
+// validDebugToken reports whether token was made with secret, hasn't expired and doesn't expire
+// more than maxTTL after now, so that a leaked token can't be used for long
+func validDebugToken(secret string, token string, now time.Time, maxTTL time.Duration) bool {
+	if secret == "" || token == "" {
+		return false
+	}
+	expiry, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	expiresAt, err := strconv.ParseInt(expiry, 10, 64)
+	if err != nil || now.Unix() >= expiresAt || expiresAt > now.Add(maxTTL).Unix() {
+		return false
+	}
+	sig, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(sig, signDebugToken(secret, expiry))
+}
+
+// debugLogger returns logger on a clone of its Logger at debug level, for a request in debug mode.
+// The clone has the same output, formatter and hooks, and takes the original's lock to write to
+// them, so that its lines aren't interleaved with others and hooks see their true level.
+func debugLogger(logger *logrus.Entry) *logrus.Entry {
+	base := logger.Logger
+	debug := &logrus.Logger{
+		Formatter:    base.Formatter,
+		ReportCaller: base.ReportCaller,
+		Level:        logrus.DebugLevel,
+		ExitFunc:     base.ExitFunc,
+		Hooks:        logrus.LevelHooks{},
+	}
+	if base.IsLevelEnabled(logrus.TraceLevel) {
+		debug.Level = logrus.TraceLevel
+	}
+	// the clone's own lock is replaced by the base's, taken by lockedWriter and lockedHook
+	debug.SetNoLock()
+	withLoggerLock(base, func() {
+		debug.Out = &lockedWriter{base: base, out: base.Out}
+		for _, hooks := range base.Hooks {
+			for _, hook := range hooks {
+				debug.Hooks.Add(&lockedHook{base: base, hook: hook})
+			}
+		}
+	})
+	return debug.WithFields(logger.Data).WithField("debug", true)
+}
+
+// withLoggerLock calls fn holding logger's lock. logrus doesn't export its lock, but AddHook calls
+// the hook's Levels method while holding it.
+func withLoggerLock(logger *logrus.Logger, fn func()) {
+	logger.AddHook(lockHook(fn))
+}
+
+// lockHook adds no hook, as it has no levels
+type lockHook func()
+
+func (h lockHook) Levels() []logrus.Level {
+	h()
+	return nil
+}
+
+func (h lockHook) Fire(*logrus.Entry) error {
+	return nil
+}
+
+type lockedWriter struct {
+	base *logrus.Logger
+	out  io.Writer
+}
+
+func (w *lockedWriter) Write(b []byte) (n int, err error) {
+	withLoggerLock(w.base, func() { n, err = w.out.Write(b) })
+	return n, err
+}
+
+type lockedHook struct {
+	base *logrus.Logger
+	hook logrus.Hook
+}
+
+func (h *lockedHook) Levels() []logrus.Level {
+	return h.hook.Levels()
+}
+
+func (h *lockedHook) Fire(entry *logrus.Entry) (err error) {
+	withLoggerLock(h.base, func() { err = h.hook.Fire(entry) })
+	return err
+}
@@ -0,0 +1,170 @@
+package xecho
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+const testDebugSecret = "0123456789abcdef0123456789abcdef"
+
+func TestValidDebugToken(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	token := NewDebugToken(testDebugSecret, now.Add(time.Minute))
+
+	assert.True(t, validDebugToken(testDebugSecret, token, now, time.Hour))
+	assert.False(t, validDebugToken(testDebugSecret, token, now.Add(time.Minute), time.Hour), "expired")
+	assert.False(t, validDebugToken("another secret that is long enough", token, now, time.Hour), "wrong secret")
+	assert.False(t, validDebugToken("", token, now, time.Hour), "no secret")
+	later := NewDebugToken(testDebugSecret, now.Add(time.Hour))
+	tampered := strings.SplitN(later, ".", 2)[0] + "." + strings.SplitN(token, ".", 2)[1]
+	assert.False(t, validDebugToken(testDebugSecret, tampered, now, time.Hour), "expiry changed")
+	assert.False(t, validDebugToken(testDebugSecret, "not-a-token", now, time.Hour))
+	assert.True(t, validDebugToken(testDebugSecret, later, now, time.Hour), "expires at the max TTL")
+	assert.False(t, validDebugToken(testDebugSecret, later, now, 30*time.Minute), "expires beyond the max TTL")
+	forever := NewDebugToken(testDebugSecret, now.AddDate(100, 0, 0))
+	assert.False(t, validDebugToken(testDebugSecret, forever, now, time.Hour), "expires beyond the max TTL")
+}
+
+func TestDebugToken_TurnsOnDebugModeForOneRequest(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("upstream body"))
+	}))
+	defer upstream.Close()
+
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.DebugSecret = testDebugSecret
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders", func(c *Context) error {
+		c.Logger().Debug("debugging orders")
+		res, err := c.HttpClient.Get(upstream.URL)
+		if err != nil {
+			return err
+		}
+		_, _ = ioutil.ReadAll(res.Body)
+		return c.JSON(http.StatusOK, map[string]bool{"debug": c.IsDebug()})
+	})
+	token := NewDebugToken(testDebugSecret, time.Now().Add(time.Minute))
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/orders").
+		Header("X-Debug-Token", token).
+		Expect(t).
+		Body(`{"debug": true}`).
+		End()
+
+	logs := buffer.String()
+	assert.Contains(t, logs, "debugging orders")
+	assert.Contains(t, logs, `GET /orders HTTP/1.1`, "inbound request dump")
+	assert.Contains(t, logs, `X-Debug-Token: [REDACTED]`)
+	assert.NotContains(t, logs, token)
+	assert.Contains(t, logs, `upstream body`, "outbound response dump")
+	assert.Contains(t, logs, `"debug":true`)
+
+	for _, header := range []string{"", NewDebugToken("another secret that is long enough", time.Now().Add(time.Minute))} {
+		buffer.Reset()
+		apitest.New().
+			Handler(x.Echo).
+			Get("/orders").
+			Header("X-Debug-Token", header).
+			Expect(t).
+			Body(`{"debug": false}`).
+			End()
+		assert.NotContains(t, buffer.String(), "debugging orders")
+		assert.NotContains(t, buffer.String(), "upstream body")
+	}
+}
+
+// errorHook records the entries logged at error level, like an error reporter
+type errorHook struct {
+	entries []*logrus.Entry
+}
+
+func (h *errorHook) Levels() []logrus.Level {
+	return []logrus.Level{logrus.ErrorLevel}
+}
+
+func (h *errorHook) Fire(entry *logrus.Entry) error {
+	h.entries = append(h.entries, entry)
+	return nil
+}
+
+func TestDebugToken_LogsThroughTheSharedLoggersHooks(t *testing.T) {
+	conf := testConfig()
+	conf.DebugSecret = testDebugSecret
+	conf.LogLevel = logrus.ErrorLevel
+	x := New(conf)
+	x.logger.Logger.SetOutput(ioutil.Discard)
+	all := test.NewLocal(x.logger.Logger)
+	errors := &errorHook{}
+	x.logger.Logger.AddHook(errors)
+	x.GET("/orders", func(c *Context) error {
+		c.Logger().Debugf("debugging order %d", 1)
+		c.Logger().(*Logger).WithField("order", 1).Debug("debugging with a field")
+		c.Logger().Error("order failed")
+		return c.NoContent(http.StatusOK)
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/orders").
+		Header("X-Debug-Token", NewDebugToken(testDebugSecret, time.Now().Add(time.Minute))).
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	levels := map[string]logrus.Level{}
+	for _, entry := range all.AllEntries() {
+		levels[entry.Message] = entry.Level
+	}
+	assert.Equal(t, logrus.DebugLevel, levels["debugging order 1"])
+	assert.Equal(t, logrus.DebugLevel, levels["debugging with a field"])
+	if assert.Len(t, errors.entries, 1, "debug lines should not fire error hooks") {
+		assert.Equal(t, "order failed", errors.entries[0].Message)
+		assert.Equal(t, true, errors.entries[0].Data["debug"])
+	}
+	assert.Equal(t, logrus.ErrorLevel, x.logger.Logger.GetLevel(), "the shared logger's level is unchanged")
+}
+
+func TestDebugToken_SharesTheLoggersLock(t *testing.T) {
+	conf := testConfig()
+	conf.DebugSecret = testDebugSecret
+	x := New(conf)
+	// bytes.Buffer isn't safe for concurrent writes, so the race detector catches unlocked writes
+	buffer := &bytes.Buffer{}
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders", func(c *Context) error {
+		c.Logger().Info("listing orders")
+		return c.NoContent(http.StatusOK)
+	})
+	token := NewDebugToken(testDebugSecret, time.Now().Add(time.Minute))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(debug bool) {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if debug {
+				req.Header.Set("X-Debug-Token", token)
+			}
+			x.Echo.ServeHTTP(httptest.NewRecorder(), req)
+		}(i%2 == 0)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, strings.Count(buffer.String(), `"msg":"listing orders"`))
+}
@@ -0,0 +1,170 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/labstack/echo"
+)
+
+const redacted = "[REDACTED]"
+
+// DumpConfig controls what the debug request and response dumps may log
+type DumpConfig struct {
+	// RedactHeaders are replaced with [REDACTED], matched case-insensitively
+	RedactHeaders []string
+	// RedactFields are JSON body fields replaced with [REDACTED]. A name such as "password"
+	// matches the field at any depth, and a dotted path such as "card.number" matches from the
+	// root. Arrays are transparent, so "items.sku" matches the sku of every item.
+	RedactFields []string
+	// MaxBodySize is the most bytes of a body that are dumped, or unlimited if 0
+	MaxBodySize int
+}
+
+func DefaultDumpConfig() DumpConfig {
+	return DumpConfig{
+		RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", debugTokenHeaderName},
+		RedactFields: []string{"password", "secret", "token", "access_token", "refresh_token",
+			"card_number", "cardNumber", "cvv"},
+		MaxBodySize: 4 << 10, // 4kb
+	}
+}
+
+func (d DumpConfig) dumpRequest(r *http.Request) (string, error) {
+	body, err := readAndRestore(&r.Body)
+	if err != nil {
+		return "", err
+	}
+
+	clone := r.Clone(r.Context())
+	clone.Header = d.redactHeaders(r.Header)
+	clone.Body = nil
+	head, err := httputil.DumpRequest(clone, false)
+	if err != nil {
+		return "", err
+	}
+	return string(head) + d.body(body, r.Header.Get(echo.HeaderContentType)), nil
+}
+
+// dumpResponse dumps res, including the body if withBody is set
+func (d DumpConfig) dumpResponse(res *http.Response, withBody bool) (string, error) {
+	var body []byte
+	if withBody {
+		var err error
+		if body, err = readAndRestore(&res.Body); err != nil {
+			return "", err
+		}
+	}
+
+	clone := *res
+	clone.Header = d.redactHeaders(res.Header)
+	clone.Body = nil
+	head, err := httputil.DumpResponse(&clone, false)
+	if err != nil {
+		return "", err
+	}
+	return string(head) + d.body(body, res.Header.Get(echo.HeaderContentType)), nil
+}
+
+func (d DumpConfig) redactHeaders(header http.Header) http.Header {
+	redactedHeader := header.Clone()
+	for _, name := range d.RedactHeaders {
+		name = http.CanonicalHeaderKey(name)
+		if _, ok := redactedHeader[name]; ok {
+			redactedHeader[name] = []string{redacted}
+		}
+	}
+	return redactedHeader
+}
+
+func (d DumpConfig) body(body []byte, contentType string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if isJSONMediaType(contentType) && len(d.RedactFields) > 0 {
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			// it can't be redacted, so it may hold secrets
+			return fmt.Sprintf("[unredactable JSON body of %d bytes omitted]", len(body))
+		}
+		if b, err := json.Marshal(d.redactJSON(v, "")); err == nil {
+			body = b
+		}
+	}
+	if d.MaxBodySize > 0 && len(body) > d.MaxBodySize {
+		// cut on a rune boundary
+		n := d.MaxBodySize
+		for n > 0 && !utf8.RuneStart(body[n]) {
+			n--
+		}
+		return fmt.Sprintf("%s...[truncated %d bytes]", body[:n], len(body)-n)
+	}
+	return string(body)
+}
+
+// isJSONMediaType reports whether contentType is application/json or a +json type such as
+// application/problem+json
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == echo.MIMEApplicationJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+func (d DumpConfig) redactJSON(v interface{}, path string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			if d.isRedactedField(k, childPath) {
+				v[k] = redacted
+			} else {
+				v[k] = d.redactJSON(child, childPath)
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = d.redactJSON(child, path)
+		}
+	}
+	return v
+}
+
+func (d DumpConfig) isRedactedField(name, path string) bool {
+	for _, field := range d.RedactFields {
+		if strings.Contains(field, ".") {
+			if strings.EqualFold(field, path) {
+				return true
+			}
+		} else if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readAndRestore reads the body, replacing it so that it can be read again
+func readAndRestore(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	b, err := ioutil.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+	_ = (*body).Close()
+	*body = ioutil.NopCloser(bytes.NewReader(b))
+	return b, nil
+}
@@ -0,0 +1,101 @@
+package xecho
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDumpConfig_RedactsRequest(t *testing.T) {
+	body := `{"user":"ann","password":"hunter2","card":{"number":"4111","expiry":"01/30"},"items":[{"sku":"1","token":"t"}]}`
+	r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer abc")
+	r.Header.Set("Accept", "application/json")
+	d := DefaultDumpConfig()
+	d.RedactFields = append(d.RedactFields, "card.number")
+
+	dump, err := d.dumpRequest(r)
+
+	assert.NoError(t, err)
+	assert.Contains(t, dump, "Authorization: [REDACTED]\r\n")
+	assert.Contains(t, dump, "Accept: application/json\r\n")
+	assert.NotContains(t, dump, "Bearer abc")
+	assert.True(t, strings.HasSuffix(dump,
+		`{"card":{"expiry":"01/30","number":"[REDACTED]"},"items":[{"sku":"1","token":"[REDACTED]"}],"password":"[REDACTED]","user":"ann"}`),
+		dump)
+	remaining, _ := ioutil.ReadAll(r.Body)
+	assert.Equal(t, body, string(remaining), "the body should still be readable")
+	assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+}
+
+func TestDumpConfig_TruncatesResponseBody(t *testing.T) {
+	res := &http.Response{
+		StatusCode:    http.StatusOK,
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Set-Cookie": {"session=abc"}, "Content-Type": {"text/plain"}},
+		Body:          ioutil.NopCloser(strings.NewReader("0123456789")),
+		ContentLength: 10,
+	}
+	d := DumpConfig{RedactHeaders: []string{"set-cookie"}, MaxBodySize: 4}
+
+	dump, err := d.dumpResponse(res, true)
+
+	assert.NoError(t, err)
+	assert.Contains(t, dump, "Set-Cookie: [REDACTED]\r\n")
+	assert.True(t, strings.HasSuffix(dump, "\r\n\r\n0123...[truncated 6 bytes]"), dump)
+}
+
+func TestDumpConfig_RedactsJSONSuffixTypesAndOmitsMalformedJSON(t *testing.T) {
+	d := DefaultDumpConfig()
+
+	assert.Equal(t, `{"code":"X","token":"[REDACTED]"}`,
+		d.body([]byte(`{"code": "X", "token": "abc"}`), "application/problem+json"))
+	assert.Equal(t, `{"token":"[REDACTED]"}`,
+		d.body([]byte(`{"token": "abc"}`), "application/vnd.api+json; charset=utf-8"))
+	assert.Equal(t, "[unredactable JSON body of 17 bytes omitted]",
+		d.body([]byte(`{"token": "abc",}`), "application/json"))
+}
+
+func TestDumpConfig_TruncatesOnARuneBoundary(t *testing.T) {
+	d := DumpConfig{MaxBodySize: 4}
+
+	assert.Equal(t, "ab...[truncated 5 bytes]", d.body([]byte("ab€cd"), "text/plain"))
+}
+
+func TestDebugLogger_RedactsInboundDumps(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.IsDebug = true
+	conf.LogLevel = logrus.DebugLevel
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.POST("/login", func(c *Context) error {
+		return c.JSON(http.StatusOK, map[string]string{"access_token": "secret-token"})
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/login").
+		Header("Cookie", "session=abc").
+		JSON(`{"user": "ann", "password": "hunter2"}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"access_token": "secret-token"}`).
+		End()
+
+	logs := buffer.String()
+	assert.Contains(t, logs, "Cookie: [REDACTED]")
+	assert.NotContains(t, logs, "session=abc")
+	assert.NotContains(t, logs, "hunter2")
+	assert.NotContains(t, logs, "secret-token")
+	assert.Contains(t, logs, `\"access_token\":\"[REDACTED]\"`)
+}
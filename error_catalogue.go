@@ -0,0 +1,104 @@
+package xecho
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// ErrorDefinition documents an error code in the catalogue
+type ErrorDefinition struct {
+	Code string `json:"code" example:"NOT_FOUND"`
+	// Status is left out when StatusVaries is set
+	Status int `json:"status,omitempty" example:"404"`
+	// StatusVaries is set for codes whose status and detail are chosen when the error is returned,
+	// such as ECHO_HTTP_ERROR
+	StatusVaries bool   `json:"status_varies,omitempty"`
+	Detail       string `json:"detail" example:"Not found"`
+	Description  string `json:"description" example:"The requested resource does not exist."`
+}
+
+// ErrorRegistry is a catalogue of every error code an app can return
+type ErrorRegistry struct {
+	mu          sync.Mutex
+	definitions map[string]ErrorDefinition
+}
+
+// DefaultErrorRegistry holds the standard xecho errors and those declared with RegisterError.
+// It is served on Config.ErrorCatalogueRoute.
+var DefaultErrorRegistry = NewErrorRegistry()
+
+func NewErrorRegistry() *ErrorRegistry {
+	return &ErrorRegistry{definitions: map[string]ErrorDefinition{}}
+}
+
+// RegisterError declares an error code in DefaultErrorRegistry, returning its sentinel error
+func RegisterError(status int, code, detail, description string) (SentinelError, error) {
+	return DefaultErrorRegistry.Register(status, code, detail, description)
+}
+
+// MustRegisterError is like RegisterError but panics if the code is already registered,
+// for declaring errors in package level variables so that duplicates are caught at startup
+func MustRegisterError(status int, code, detail, description string) SentinelError {
+	return DefaultErrorRegistry.MustRegister(status, code, detail, description)
+}
+
+// Register declares an error code, returning its sentinel error. It fails if code is already
+// registered, so that errors.Is, which compares codes, can't match an unrelated error.
+func (r *ErrorRegistry) Register(status int, code, detail, description string) (SentinelError, error) {
+	if status < 100 || status > 599 {
+		return SentinelError{}, fmt.Errorf("xecho: error code %s has invalid status %d", code, status)
+	}
+	definition := ErrorDefinition{Code: code, Status: status, Detail: detail, Description: description}
+	if err := r.add(definition); err != nil {
+		return SentinelError{}, err
+	}
+	return SentinelError{NewError(status, code, detail)}, nil
+}
+
+// mustRegisterVarying declares an error code whose status and detail are chosen when it is returned
+func (r *ErrorRegistry) mustRegisterVarying(code, description string) {
+	if err := r.add(ErrorDefinition{Code: code, StatusVaries: true, Description: description}); err != nil {
+		panic(err)
+	}
+}
+
+func (r *ErrorRegistry) add(definition ErrorDefinition) error {
+	if definition.Code == "" {
+		return fmt.Errorf("xecho: error code must not be empty")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[definition.Code]; ok {
+		return fmt.Errorf("xecho: error code %s is already registered", definition.Code)
+	}
+	r.definitions[definition.Code] = definition
+	return nil
+}
+
+func (r *ErrorRegistry) MustRegister(status int, code, detail, description string) SentinelError {
+	err, regErr := r.Register(status, code, detail, description)
+	if regErr != nil {
+		panic(regErr)
+	}
+	return err
+}
+
+// Definitions returns every registered error, ordered by code
+func (r *ErrorRegistry) Definitions() []ErrorDefinition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	definitions := make([]ErrorDefinition, 0, len(r.definitions))
+	for _, d := range r.definitions {
+		definitions = append(definitions, d)
+	}
+	sort.Slice(definitions, func(i, j int) bool { return definitions[i].Code < definitions[j].Code })
+	return definitions
+}
+
+// Handler serves the catalogue as JSON
+func (r *ErrorRegistry) Handler(c *Context) error {
+	return c.JSON(http.StatusOK, map[string]interface{}{"errors": r.Definitions()})
+}
@@ -0,0 +1,79 @@
+package xecho
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorRegistry_Register(t *testing.T) {
+	r := NewErrorRegistry()
+
+	err, regErr := r.Register(http.StatusConflict, "ORDER_LOCKED", "Order is locked", "The order is being picked.")
+	assert.NoError(t, regErr)
+	assert.Equal(t, NewError(http.StatusConflict, "ORDER_LOCKED", "Order is locked"), err.New())
+
+	_, regErr = r.Register(http.StatusBadRequest, "ORDER_LOCKED", "Order is locked", "")
+	assert.EqualError(t, regErr, "xecho: error code ORDER_LOCKED is already registered")
+	assert.Panics(t, func() { r.MustRegister(http.StatusBadRequest, "ORDER_LOCKED", "", "") })
+
+	_, regErr = r.Register(http.StatusBadRequest, "", "", "")
+	assert.Error(t, regErr)
+	_, regErr = r.Register(0, "ORDER_STUCK", "", "")
+	assert.EqualError(t, regErr, "xecho: error code ORDER_STUCK has invalid status 0")
+
+	r.MustRegister(http.StatusGone, "ORDER_ARCHIVED", "Order is archived", "The order is too old to view.")
+	assert.Equal(t, []ErrorDefinition{
+		{Code: "ORDER_ARCHIVED", Status: http.StatusGone, Detail: "Order is archived", Description: "The order is too old to view."},
+		{Code: "ORDER_LOCKED", Status: http.StatusConflict, Detail: "Order is locked", Description: "The order is being picked."},
+	}, r.Definitions())
+}
+
+func TestDefaultErrorRegistry_HasStandardErrors(t *testing.T) {
+	codes := map[string]int{}
+	for _, d := range DefaultErrorRegistry.Definitions() {
+		codes[d.Code] = d.Status
+	}
+
+	for _, sentinel := range []SentinelError{ErrBadRequest, ErrUnauthorised, ErrForbidden, ErrNotFound, ErrMethodNotAllowed,
+		ErrConflict, ErrPreconditionFailed, ErrValidationFailed, ErrTooManyRequests, ErrInternalServer,
+		ErrServiceUnavailable, ErrGatewayTimeout} {
+		err := sentinel.New()
+		assert.Equal(t, err.Status, codes[err.Code], err.Code)
+	}
+	assert.Panics(t, func() { MustRegisterError(http.StatusNotFound, "NOT_FOUND", "", "") })
+}
+
+func TestErrorCatalogueRoute(t *testing.T) {
+	conf := testConfig()
+	conf.ErrorCatalogueRoute = "/errors"
+	x := New(conf)
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/errors").
+		Expect(t).
+		Status(http.StatusOK).
+		Assert(func(res *http.Response, _ *http.Request) error {
+			var body struct {
+				Errors []ErrorDefinition `json:"errors"`
+			}
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&body))
+			assert.Contains(t, body.Errors, ErrorDefinition{
+				Code:        "TOO_MANY_REQUESTS",
+				Status:      http.StatusTooManyRequests,
+				Detail:      "Too many requests",
+				Description: "The client has sent too many requests and should retry later.",
+			})
+			assert.Contains(t, body.Errors, ErrorDefinition{
+				Code:         "ECHO_HTTP_ERROR",
+				StatusVaries: true,
+				Description:  "An error returned by the router, such as an unmatched route. The status and detail vary.",
+			})
+			return nil
+		}).
+		End()
+}
@@ -0,0 +1,145 @@
+package xecho
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ErrorFormatDefault writes errors as {code, detail} with DefaultErrorHandler
+	ErrorFormatDefault = "default"
+	// ErrorFormatProblem writes RFC 7807 problem details with ProblemErrorHandler
+	ErrorFormatProblem = "problem"
+	// ErrorFormatJSONAPI writes JSON:API error objects with JSONAPIErrorHandler
+	ErrorFormatJSONAPI = "jsonapi"
+)
+
+var errorFormats = []string{ErrorFormatDefault, ErrorFormatProblem, ErrorFormatJSONAPI}
+
+const (
+	mimeProblemJSON = "application/problem+json"
+	mimeJSONAPI     = "application/vnd.api+json"
+)
+
+// ErrorHandlerForFormat returns the built-in error handler for one of the ErrorFormat constants.
+// problemTypeBaseURI is passed to ProblemErrorHandler for ErrorFormatProblem.
+func ErrorHandlerForFormat(format string, problemTypeBaseURI string) (ErrorHandlerFunc, error) {
+	switch strings.ToLower(format) {
+	case ErrorFormatDefault:
+		return DefaultErrorHandler(), nil
+	case ErrorFormatProblem:
+		return ProblemErrorHandler(problemTypeBaseURI), nil
+	case ErrorFormatJSONAPI:
+		return JSONAPIErrorHandler(), nil
+	}
+	return nil, fmt.Errorf("unknown error format %q", format)
+}
+
+// ProblemErrorHandler writes errors as RFC 7807 application/problem+json. The type is typeBaseURI
+// followed by the error code in lower case, e.g. https://errors.example.com/not_found, or
+// about:blank if typeBaseURI is empty. Error.Code, Error.Violations and the public Error.Params
+// are added as extension members, and instance identifies the request by its correlation ID.
+func ProblemErrorHandler(typeBaseURI string) ErrorHandlerFunc {
+	return func(c *Context, err *Error) {
+		problemType := "about:blank"
+		if typeBaseURI != "" {
+			problemType = typeBaseURI + strings.ToLower(err.Code)
+		}
+
+		problem := map[string]interface{}{}
+		for k, v := range err.publicParams() {
+			problem[k] = v
+		}
+		problem["type"] = problemType
+		problem["title"] = http.StatusText(err.Status)
+		problem["status"] = err.Status
+		problem["detail"] = err.Detail
+		problem["code"] = err.Code
+		if c.CorrelationID != "" {
+			problem["instance"] = "urn:correlation-id:" + c.CorrelationID
+		}
+		if len(err.Violations) > 0 {
+			problem["violations"] = err.Violations
+		}
+
+		writeErrorJSON(c, err.Status, mimeProblemJSON, problem)
+	}
+}
+
+type jsonAPIErrors struct {
+	Errors []jsonAPIError `json:"errors"`
+}
+
+type jsonAPIError struct {
+	ID     string              `json:"id,omitempty"`
+	Status string              `json:"status"`
+	Code   string              `json:"code"`
+	Title  string              `json:"title"`
+	Detail string              `json:"detail"`
+	Source *jsonAPIErrorSource `json:"source,omitempty"`
+	Meta   map[string]string   `json:"meta,omitempty"`
+}
+
+type jsonAPIErrorSource struct {
+	Pointer   string `json:"pointer,omitempty"`
+	Parameter string `json:"parameter,omitempty"`
+	Header    string `json:"header,omitempty"`
+}
+
+// JSONAPIErrorHandler writes errors as JSON:API error objects, one for each violation if there are any.
+// The id is the correlation ID and the public Error.Params are added as meta.
+func JSONAPIErrorHandler() ErrorHandlerFunc {
+	return func(c *Context, err *Error) {
+		base := jsonAPIError{
+			ID:     c.CorrelationID,
+			Status: strconv.Itoa(err.Status),
+			Code:   err.Code,
+			Title:  http.StatusText(err.Status),
+			Detail: err.Detail,
+			Meta:   err.publicParams(),
+		}
+
+		body := jsonAPIErrors{}
+		for _, v := range err.Violations {
+			e := base
+			e.Detail = fmt.Sprintf("%s %s", v.Field, v.Message)
+			e.Source = jsonAPISource(v)
+			body.Errors = append(body.Errors, e)
+		}
+		if len(body.Errors) == 0 {
+			body.Errors = []jsonAPIError{base}
+		}
+
+		writeErrorJSON(c, err.Status, mimeJSONAPI, body)
+	}
+}
+
+func jsonAPISource(v FieldViolation) *jsonAPIErrorSource {
+	switch v.Location {
+	case "path", "query":
+		return &jsonAPIErrorSource{Parameter: v.Field}
+	case "header":
+		return &jsonAPIErrorSource{Header: v.Field}
+	}
+	return &jsonAPIErrorSource{Pointer: jsonPointer(v.Field)}
+}
+
+// jsonPointer converts a field path such as items[0].name to /items/0/name
+func jsonPointer(field string) string {
+	field = strings.NewReplacer("~", "~0", "/", "~1").Replace(field)
+	field = strings.NewReplacer("[", ".", "]", "").Replace(field)
+	return "/" + strings.ReplaceAll(field, ".", "/")
+}
+
+func writeErrorJSON(c *Context, status int, contentType string, body interface{}) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		c.Logger().Errorf("failed to encode error response: %+v", err)
+		c.Response().WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_ = c.Blob(status, contentType, b)
+}
@@ -0,0 +1,127 @@
+package xecho_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/JSainsburyPLC/xecho"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+var errOutOfStock = &xecho.Error{
+	Status:       http.StatusConflict,
+	Code:         "OUT_OF_STOCK",
+	Detail:       "Item is out of stock",
+	Params:       map[string]string{"sku": "123", "reason": "warehouse 4 empty"},
+	PublicParams: []string{"sku"},
+}
+
+func TestProblemErrorHandler(t *testing.T) {
+	conf := config("")
+	conf.ErrorHandler = xecho.ProblemErrorHandler("https://errors.example.com/")
+	x := xecho.New(conf)
+	x.GET("/items", func(c *xecho.Context) error { return errOutOfStock })
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/items").
+		Header("Correlation-Id", "abc-123").
+		Expect(t).
+		Status(http.StatusConflict).
+		Header("Content-Type", "application/problem+json").
+		Body(`{
+			"type": "https://errors.example.com/out_of_stock",
+			"title": "Conflict",
+			"status": 409,
+			"detail": "Item is out of stock",
+			"instance": "urn:correlation-id:abc-123",
+			"code": "OUT_OF_STOCK",
+			"sku": "123"
+		}`).
+		End()
+}
+
+func TestJSONAPIErrorHandler(t *testing.T) {
+	conf := config("")
+	conf.ErrorHandler = xecho.JSONAPIErrorHandler()
+	x := xecho.New(conf)
+	x.GET("/items", func(c *xecho.Context) error { return errOutOfStock })
+	x.POST("/items", func(c *xecho.Context) error {
+		return &xecho.Error{
+			Status: http.StatusUnprocessableEntity,
+			Code:   "VALIDATION_FAILED",
+			Detail: "Validation failed",
+			Violations: []xecho.FieldViolation{
+				{Field: "lines[0].sku", Location: "body", Message: "is required"},
+				{Field: "page", Location: "query", Message: "must be an integer"},
+			},
+		}
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/items").
+		Header("Correlation-Id", "abc-123").
+		Expect(t).
+		Status(http.StatusConflict).
+		Header("Content-Type", "application/vnd.api+json").
+		Body(`{"errors": [{
+			"id": "abc-123",
+			"status": "409",
+			"code": "OUT_OF_STOCK",
+			"title": "Conflict",
+			"detail": "Item is out of stock",
+			"meta": {"sku": "123"}
+		}]}`).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Post("/items").
+		Header("Correlation-Id", "abc-123").
+		Expect(t).
+		Status(http.StatusUnprocessableEntity).
+		Body(`{"errors": [
+			{"id": "abc-123", "status": "422", "code": "VALIDATION_FAILED", "title": "Unprocessable Entity",
+				"detail": "lines[0].sku is required", "source": {"pointer": "/lines/0/sku"}},
+			{"id": "abc-123", "status": "422", "code": "VALIDATION_FAILED", "title": "Unprocessable Entity",
+				"detail": "page must be an integer", "source": {"parameter": "page"}}
+		]}`).
+		End()
+}
+
+func TestErrorHandlerForFormat(t *testing.T) {
+	for _, format := range []string{"default", "problem", "JSONAPI"} {
+		handler, err := xecho.ErrorHandlerForFormat(format, "")
+		assert.NoError(t, err)
+		assert.NotNil(t, handler)
+	}
+
+	_, err := xecho.ErrorHandlerForFormat("xml", "")
+	assert.EqualError(t, err, `unknown error format "xml"`)
+}
+
+func TestConfig_ErrorFormat(t *testing.T) {
+	conf := config("")
+	conf.ErrorHandler = nil
+	conf.ErrorFormat = xecho.ErrorFormatProblem
+	conf.ProblemTypeBaseURI = "https://errors.example.com/"
+	assert.NoError(t, conf.Validate())
+	x := xecho.New(conf)
+	x.GET("/items", func(c *xecho.Context) error { return errOutOfStock })
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/items").
+		Expect(t).
+		Status(http.StatusConflict).
+		Header("Content-Type", "application/problem+json").
+		Assert(func(res *http.Response, _ *http.Request) error {
+			var problem map[string]interface{}
+			assert.NoError(t, json.NewDecoder(res.Body).Decode(&problem))
+			assert.Equal(t, "https://errors.example.com/out_of_stock", problem["type"])
+			return nil
+		}).
+		End()
+}
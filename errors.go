@@ -1,20 +1,30 @@
 package xecho
 
 import (
+	"errors"
 	"fmt"
 	"github.com/labstack/echo"
 	"net/http"
-	"runtime"
+	"strconv"
 	"strings"
 )
 
-const stackSize = 4 << 10 // 4kb
-
+// Error is an error with an HTTP status that is shown to clients. It is customised with the With
+// methods and Wrap, which return copies.
 type Error struct {
 	Status int               `json:"-" example:"400"`
 	Code   string            `json:"code" example:"BAD_REQUEST"`
 	Detail string            `json:"detail" example:"Bad request"`
 	Params map[string]string `json:"-"`
+	// PublicParams names the Params that may be shown to clients, by the problem and JSON:API error handlers
+	PublicParams []string `json:"-"`
+	// Violations lists the request fields at fault, for bad requests
+	Violations []FieldViolation `json:"violations,omitempty"`
+	cause      error
+}
+
+func NewError(status int, code string, detail string) *Error {
+	return &Error{Status: status, Code: code, Detail: detail}
 }
 
 func (err *Error) Error() string {
@@ -24,41 +34,222 @@ func (err *Error) Error() string {
 	for k, v := range err.Params {
 		errorParts = append(errorParts, fmt.Sprintf("%s: %s", k, v))
 	}
+	for _, v := range err.Violations {
+		errorParts = append(errorParts, fmt.Sprintf("%s %s: %s", v.Location, v.Field, v.Message))
+	}
 	return strings.Join(errorParts, "; ")
 }
 
+// WithDetail returns a copy of err with detail
+func (err *Error) WithDetail(detail string) *Error {
+	e := err.clone()
+	e.Detail = detail
+	return e
+}
+
+// WithParam returns a copy of err with the param, which is logged but not shown to clients
+func (err *Error) WithParam(key, value string) *Error {
+	e := err.clone()
+	e.setParam(key, value)
+	return e
+}
+
+// WithPublicParam returns a copy of err with the param, which may be shown to clients
+func (err *Error) WithPublicParam(key, value string) *Error {
+	e := err.WithParam(key, value)
+	e.PublicParams = append(e.PublicParams, key)
+	return e
+}
+
+// WithViolations returns a copy of err with violations added
+func (err *Error) WithViolations(violations ...FieldViolation) *Error {
+	e := err.clone()
+	e.Violations = append(e.Violations, violations...)
+	return e
+}
+
+// Wrap returns a copy of err caused by cause, which is logged as the reason
+func (err *Error) Wrap(cause error) *Error {
+	e := err.clone()
+	e.cause = cause
+	if _, ok := e.Params["reason"]; !ok && cause != nil {
+		e.setParam("reason", cause.Error())
+	}
+	return e
+}
+
+func (err *Error) Unwrap() error {
+	return err.cause
+}
+
+// Is reports whether target is an *Error or SentinelError with the same code, so that copies of a
+// sentinel match it with errors.Is. Codes identify errors as registering one fails if it is taken.
+func (err *Error) Is(target error) bool {
+	switch t := target.(type) {
+	case *Error:
+		return t.Code == err.Code
+	case SentinelError:
+		return t.err.Code == err.Code
+	}
+	return false
+}
+
+func (err *Error) clone() *Error {
+	e := *err
+	e.Params = nil
+	for k, v := range err.Params {
+		e.setParam(k, v)
+	}
+	e.PublicParams = append([]string(nil), err.PublicParams...)
+	e.Violations = append([]FieldViolation(nil), err.Violations...)
+	return &e
+}
+
+// SentinelError is a registered error, such as ErrNotFound. It is shared, so it can't be modified:
+// New, the With methods and Wrap return an *Error copy to customise.
+type SentinelError struct {
+	err *Error
+}
+
+// New returns a copy of the error
+func (s SentinelError) New() *Error {
+	return s.err.clone()
+}
+
+func (s SentinelError) Error() string {
+	return s.err.Error()
+}
+
+// Is reports whether target has the same code, as for Error.Is
+func (s SentinelError) Is(target error) bool {
+	return s.err.Is(target)
+}
+
+// WithDetail returns a copy of the error with detail
+func (s SentinelError) WithDetail(detail string) *Error {
+	return s.err.WithDetail(detail)
+}
+
+// WithParam returns a copy of the error with the param, which is logged but not shown to clients
+func (s SentinelError) WithParam(key, value string) *Error {
+	return s.err.WithParam(key, value)
+}
+
+// WithPublicParam returns a copy of the error with the param, which may be shown to clients
+func (s SentinelError) WithPublicParam(key, value string) *Error {
+	return s.err.WithPublicParam(key, value)
+}
+
+// WithViolations returns a copy of the error with violations added
+func (s SentinelError) WithViolations(violations ...FieldViolation) *Error {
+	return s.err.WithViolations(violations...)
+}
+
+// Wrap returns a copy of the error caused by cause, which is logged as the reason
+func (s SentinelError) Wrap(cause error) *Error {
+	return s.err.Wrap(cause)
+}
+
+func (err *Error) setParam(key, value string) {
+	if err.Params == nil {
+		err.Params = map[string]string{}
+	}
+	err.Params[key] = value
+}
+
+// ErrorClass groups errors by code in New Relic error analytics
+func (err *Error) ErrorClass() string {
+	return err.Code
+}
+
+// ErrorAttributes are added to the error when it is noticed in New Relic
+func (err *Error) ErrorAttributes() map[string]interface{} {
+	attributes := map[string]interface{}{
+		"status": err.Status,
+		"detail": err.Detail,
+	}
+	for k, v := range err.Params {
+		attributes[k] = v
+	}
+	return attributes
+}
+
+func (err *Error) publicParams() map[string]string {
+	if len(err.PublicParams) == 0 {
+		return nil
+	}
+	params := map[string]string{}
+	for _, k := range err.PublicParams {
+		if v, ok := err.Params[k]; ok {
+			params[k] = v
+		}
+	}
+	return params
+}
+
 type ErrorHandlerFunc func(c *Context, err *Error)
 
+type ErrorHandlerConfig struct {
+	ErrorHandler ErrorHandlerFunc
+	// ReportStatus returns true for the statuses whose errors are noticed on the request's
+	// transaction, and so count towards the error rate, including statuses written by a handler
+	// without returning an error. When nil no errors are noticed.
+	ReportStatus func(status int) bool
+}
+
 func ErrorHandlerMiddleware(errorHandler ErrorHandlerFunc) echo.MiddlewareFunc {
+	return ErrorHandlerMiddlewareWithConfig(ErrorHandlerConfig{ErrorHandler: errorHandler})
+}
+
+func ErrorHandlerMiddlewareWithConfig(conf ErrorHandlerConfig) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			err := next(c)
+			cc := c.(*Context)
 			if err != nil {
-				cc := c.(*Context)
-				handleError(errorHandler, cc, err)
+				handleError(conf.ErrorHandler, cc, err, conf.ReportStatus)
+			} else {
+				noticeWrittenStatus(cc, conf.ReportStatus)
 			}
 			return nil
 		}
 	}
 }
 
-func PanicHandlerMiddleware(errorHandler ErrorHandlerFunc) echo.MiddlewareFunc {
-	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return func(c echo.Context) error {
-			defer func() {
-				if r := recover(); r != nil {
-					err, ok := r.(error)
-					if !ok {
-						err = fmt.Errorf("%v", r)
-					}
-					stack := make([]byte, stackSize)
-					length := runtime.Stack(stack, true)
-					c.Logger().Printf("[PANIC RECOVER] %v %s\n", err, stack[:length])
-					handleError(errorHandler, c.(*Context), err)
-				}
-			}()
-			return next(c)
+// ErrorStatusRule reports 4xx and 5xx statuses as errors, except those in ignored
+// and, if ignoreClientErrors is set, all 4xx statuses
+func ErrorStatusRule(ignored []int, ignoreClientErrors bool) func(status int) bool {
+	ignore := map[int]bool{}
+	for _, status := range ignored {
+		ignore[status] = true
+	}
+	return func(status int) bool {
+		if status < http.StatusBadRequest || ignore[status] {
+			return false
 		}
+		return !ignoreClientErrors || status >= http.StatusInternalServerError
+	}
+}
+
+// statusError is noticed for a reported status written by a handler rather than returned as an error
+type statusError int
+
+func (s statusError) Error() string {
+	return fmt.Sprintf("%d %s", int(s), http.StatusText(int(s)))
+}
+
+// ErrorClass groups these errors by status in New Relic, as its agent does for response statuses
+func (s statusError) ErrorClass() string {
+	return strconv.Itoa(int(s))
+}
+
+func noticeWrittenStatus(c *Context, reportStatus func(status int) bool) {
+	status := c.Response().Status
+	if !c.Response().Committed || reportStatus == nil || !reportStatus(status) || c.Transaction == nil {
+		return
+	}
+	if err := c.Transaction.NoticeError(statusError(status)); err != nil {
+		c.Logger().Errorf("failed to notice error on tx: %+v", err)
 	}
 }
 
@@ -68,63 +259,94 @@ func DefaultErrorHandler() ErrorHandlerFunc {
 	}
 }
 
-func handleError(errorHandler ErrorHandlerFunc, c *Context, err error) {
+func handleError(errorHandler ErrorHandlerFunc, c *Context, err error, reportStatus func(status int) bool) {
 	var newErr *Error
-	switch err := err.(type) {
-	case *Error:
-		newErr = err
-	case *echo.HTTPError:
-		newErr = &Error{
-			Status: err.Code,
-			Code:   "ECHO_HTTP_ERROR",
-			Detail: fmt.Sprintf("%v", err.Message),
-			Params: map[string]string{"reason": err.Error()},
+	var xerr *Error
+	var sentinel SentinelError
+	var httpErr *echo.HTTPError
+	switch {
+	case errors.As(err, &xerr):
+		newErr = xerr.clone()
+		if xerr != err {
+			// keep the context added by the errors wrapping it
+			newErr = newErr.Wrap(err)
 		}
-	default:
-		newErr = &Error{
-			Status: ErrInternalServer.Status,
-			Code:   ErrInternalServer.Code,
-			Detail: ErrInternalServer.Detail,
-			Params: map[string]string{"reason": err.Error()},
+	case errors.As(err, &sentinel):
+		newErr = sentinel.New()
+		if sentinel != err {
+			newErr = newErr.Wrap(err)
 		}
+	case errors.As(err, &httpErr):
+		newErr = NewError(httpErr.Code, echoHTTPErrorCode, fmt.Sprintf("%v", httpErr.Message)).Wrap(err)
+	default:
+		newErr = ErrInternalServer.Wrap(err)
 	}
-	recordError(newErr, c)
+	recordError(newErr, c, reportStatus != nil && reportStatus(newErr.Status))
 	errorHandler(c, newErr)
 }
 
-func recordError(err *Error, c *Context) {
-	c.Logger().Error(err)
-	c.AddNewRelicAttribute("errorCode", err.Code)
-	c.AddNewRelicAttribute("errorDetail", err.Detail)
-	c.AddNewRelicAttribute("errorReason", err.Params["reason"])
+func recordError(err *Error, c *Context, notice bool) {
+	// recovered panics have already been logged with their report by the panic handler
+	var recovered recoveredPanic
+	if !errors.As(err, &recovered) {
+		c.Logger().Error(err)
+	}
+	c.AddTraceAttribute("errorCode", err.Code)
+	c.AddTraceAttribute("errorDetail", err.Detail)
+	c.AddTraceAttribute("errorReason", err.Params["reason"])
+	if notice && c.Transaction != nil {
+		if noticeErr := c.Transaction.NoticeError(err); noticeErr != nil {
+			c.Logger().Errorf("failed to notice error on tx: %+v", noticeErr)
+		}
+	}
 }
 
-var ErrInternalServer = &Error{
-	Status: http.StatusInternalServerError,
-	Code:   "INTERNAL_SERVER_ERROR",
-	Detail: "Internal server error",
-}
+// echoHTTPErrorCode is used for errors returned by echo other than those from its binder
+const echoHTTPErrorCode = "ECHO_HTTP_ERROR"
 
-var ErrBadRequest = &Error{
-	Status: http.StatusBadRequest,
-	Code:   "BAD_REQUEST",
-	Detail: "Bad request",
+func init() {
+	DefaultErrorRegistry.mustRegisterVarying(echoHTTPErrorCode,
+		"An error returned by the router, such as an unmatched route. The status and detail vary.")
 }
 
-var ErrUnauthorised = &Error{
-	Status: http.StatusUnauthorized,
-	Code:   "UNAUTHORISED",
-	Detail: "Unauthorised",
-}
+var ErrInternalServer = MustRegisterError(http.StatusInternalServerError, "INTERNAL_SERVER_ERROR",
+	"Internal server error", "An unexpected error occurred.")
 
-var ErrNotFound = &Error{
-	Status: http.StatusNotFound,
-	Code:   "NOT_FOUND",
-	Detail: "Not found",
-}
+var ErrPanic = MustRegisterError(http.StatusInternalServerError, "PANIC",
+	"Internal server error", "The request handler panicked. A panic report is logged.")
 
-var ErrMethodNotAllowed = &Error{
-	Status: http.StatusMethodNotAllowed,
-	Code:   "METHOD_NOT_ALLOWED",
-	Detail: "Method not allowed",
-}
+var ErrBadRequest = MustRegisterError(http.StatusBadRequest, "BAD_REQUEST",
+	"Bad request", "The request could not be processed.")
+
+var ErrInvalidRequest = MustRegisterError(http.StatusBadRequest, "INVALID_REQUEST",
+	"Invalid request", "The request could not be decoded, e.g. malformed JSON or a parameter of the wrong type.")
+
+var ErrValidationFailed = MustRegisterError(http.StatusUnprocessableEntity, "VALIDATION_FAILED",
+	"Validation failed", "The request was decoded but one or more fields are invalid.")
+
+var ErrUnauthorised = MustRegisterError(http.StatusUnauthorized, "UNAUTHORISED",
+	"Unauthorised", "The request is missing valid credentials.")
+
+var ErrForbidden = MustRegisterError(http.StatusForbidden, "FORBIDDEN",
+	"Forbidden", "The credentials do not allow the request.")
+
+var ErrNotFound = MustRegisterError(http.StatusNotFound, "NOT_FOUND",
+	"Not found", "The requested resource does not exist.")
+
+var ErrMethodNotAllowed = MustRegisterError(http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED",
+	"Method not allowed", "The resource does not support the request method.")
+
+var ErrConflict = MustRegisterError(http.StatusConflict, "CONFLICT",
+	"Conflict", "The request conflicts with the current state of the resource.")
+
+var ErrPreconditionFailed = MustRegisterError(http.StatusPreconditionFailed, "PRECONDITION_FAILED",
+	"Precondition failed", "A conditional header such as If-Match did not match the resource.")
+
+var ErrTooManyRequests = MustRegisterError(http.StatusTooManyRequests, "TOO_MANY_REQUESTS",
+	"Too many requests", "The client has sent too many requests and should retry later.")
+
+var ErrServiceUnavailable = MustRegisterError(http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE",
+	"Service unavailable", "The service is temporarily unable to handle the request.")
+
+var ErrGatewayTimeout = MustRegisterError(http.StatusGatewayTimeout, "GATEWAY_TIMEOUT",
+	"Gateway timeout", "An upstream dependency did not respond in time.")
@@ -1,6 +1,10 @@
 package xecho
 
 import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo"
+	"github.com/steinfletcher/apitest"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
@@ -20,3 +24,150 @@ func TestError_Format(t *testing.T) {
 
 	assert.Equal(t, "Code: MY_ERROR; Status: 500; Detail: My Error; Reason: private reason", errString)
 }
+
+func TestError_WithMethodsReturnCopies(t *testing.T) {
+	err := ErrNotFound.WithDetail("Order not found").WithParam("order", "123").WithPublicParam("sku", "456")
+
+	assert.Equal(t, &Error{
+		Status:       http.StatusNotFound,
+		Code:         "NOT_FOUND",
+		Detail:       "Order not found",
+		Params:       map[string]string{"order": "123", "sku": "456"},
+		PublicParams: []string{"sku"},
+	}, err)
+	assert.Equal(t, NewError(http.StatusNotFound, "NOT_FOUND", "Not found"), ErrNotFound.New())
+}
+
+func TestSentinelError_NewReturnsCopies(t *testing.T) {
+	err := ErrNotFound.New()
+	err.Detail = "Order not found"
+	err.Params = map[string]string{"order": "123"}
+
+	assert.Equal(t, NewError(http.StatusNotFound, "NOT_FOUND", "Not found"), ErrNotFound.New())
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(ErrNotFound, err))
+	assert.True(t, errors.Is(ErrNotFound, ErrNotFound))
+	assert.False(t, errors.Is(ErrNotFound, ErrConflict))
+}
+
+func TestError_WrapAndIs(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("loading order: %w", ErrInternalServer.Wrap(cause))
+
+	assert.True(t, errors.Is(err, ErrInternalServer))
+	assert.True(t, errors.Is(err, cause))
+	assert.False(t, errors.Is(err, ErrNotFound))
+	var xerr *Error
+	assert.True(t, errors.As(err, &xerr))
+	assert.Equal(t, "connection refused", xerr.Params["reason"])
+}
+
+func TestHandleError_FindsWrappedError(t *testing.T) {
+	ctx, _, _ := getEchoTestCtx()
+	c := &Context{Context: ctx, logger: &Logger{NullLogger().WithField("test", true)}}
+	var handled *Error
+
+	handleError(func(c *Context, err *Error) { handled = err }, c, fmt.Errorf("loading order: %w", ErrNotFound), nil)
+
+	assert.Equal(t, http.StatusNotFound, handled.Status)
+	assert.Equal(t, "NOT_FOUND", handled.Code)
+	assert.Equal(t, "loading order: Code: NOT_FOUND; Status: 404; Detail: Not found", handled.Params["reason"])
+	assert.Nil(t, ErrNotFound.New().Params)
+
+	handleError(func(c *Context, err *Error) { handled = err }, c, ErrUnauthorised, nil)
+
+	assert.Equal(t, NewError(http.StatusUnauthorized, "UNAUTHORISED", "Unauthorised"), handled)
+}
+
+func TestErrorStatusRule(t *testing.T) {
+	tests := []struct {
+		ignored            []int
+		ignoreClientErrors bool
+		status             int
+		expected           bool
+	}{
+		{nil, false, http.StatusOK, false},
+		{nil, false, http.StatusNotFound, true},
+		{[]int{http.StatusNotFound}, false, http.StatusNotFound, false},
+		{[]int{http.StatusNotFound}, false, http.StatusConflict, true},
+		{nil, true, http.StatusConflict, false},
+		{nil, true, http.StatusBadGateway, true},
+		{[]int{http.StatusServiceUnavailable}, true, http.StatusServiceUnavailable, false},
+	}
+	for _, test := range tests {
+		rule := ErrorStatusRule(test.ignored, test.ignoreClientErrors)
+		assert.Equal(t, test.expected, rule(test.status), "%v %v %d", test.ignored, test.ignoreClientErrors, test.status)
+	}
+}
+
+func TestAllErrorStatuses_LeavesNoticingToTheErrorHandler(t *testing.T) {
+	statuses := allErrorStatuses()
+
+	for status := http.StatusBadRequest; status <= 599; status++ {
+		assert.Contains(t, statuses, status)
+	}
+}
+
+func TestErrorHandler_NoticesEachErrorOnce(t *testing.T) {
+	tracer := NewRecordingTracer()
+	conf := testConfig()
+	conf.Tracer = tracer
+	x := New(conf)
+	x.GET("/returned", func(c *Context) error { return ErrServiceUnavailable })
+	x.GET("/written", func(c *Context) error {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"status": "down"})
+	})
+	x.GET("/ignored", func(c *Context) error { return c.NoContent(http.StatusNotFound) })
+
+	apitest.New().Handler(x.Echo).Get("/returned").Expect(t).Status(http.StatusServiceUnavailable).End()
+	apitest.New().Handler(x.Echo).Get("/written").Expect(t).Status(http.StatusServiceUnavailable).End()
+	apitest.New().Handler(x.Echo).Get("/ignored").Expect(t).Status(http.StatusNotFound).End()
+
+	txns := tracer.Transactions()
+	if assert.Len(t, txns[0].Errors, 1) {
+		assert.Equal(t, "SERVICE_UNAVAILABLE", txns[0].Errors[0].(*Error).ErrorClass())
+	}
+	if assert.Len(t, txns[1].Errors, 1) {
+		assert.Equal(t, "503", txns[1].Errors[0].(statusError).ErrorClass())
+	}
+	assert.Empty(t, txns[2].Errors)
+}
+
+func TestErrorHandler_NoticesErrorsByStatusRule(t *testing.T) {
+	tracer := NewRecordingTracer()
+	conf := testConfig()
+	conf.Tracer = tracer
+	conf.IgnoreClientErrors = true
+	x := New(conf)
+	x.GET("/missing", func(c *Context) error { return ErrConflict })
+	x.GET("/broken", func(c *Context) error {
+		return ErrServiceUnavailable.WithParam("dependency", "stock")
+	})
+
+	apitest.New().Handler(x.Echo).Get("/missing").Expect(t).Status(http.StatusConflict).End()
+	apitest.New().Handler(x.Echo).Get("/broken").Expect(t).Status(http.StatusServiceUnavailable).End()
+
+	txns := tracer.Transactions()
+	assert.Empty(t, txns[0].Errors)
+	assert.Equal(t, "CONFLICT", txns[0].Attributes["errorCode"])
+	assert.Len(t, txns[1].Errors, 1)
+	noticed := txns[1].Errors[0].(*Error)
+	assert.Equal(t, "SERVICE_UNAVAILABLE", noticed.ErrorClass())
+	assert.Equal(t, map[string]interface{}{
+		"status":     http.StatusServiceUnavailable,
+		"detail":     "Service unavailable",
+		"dependency": "stock",
+	}, noticed.ErrorAttributes())
+}
+
+func TestHandleError_EchoBadRequestKeepsEchoCode(t *testing.T) {
+	ctx, _, _ := getEchoTestCtx()
+	c := &Context{Context: ctx, logger: &Logger{NullLogger().WithField("test", true)}}
+	var handled *Error
+
+	handleError(func(c *Context, err *Error) { handled = err }, c, echo.NewHTTPError(http.StatusBadRequest, "missing csrf token"), nil)
+
+	assert.Equal(t, http.StatusBadRequest, handled.Status)
+	assert.Equal(t, echoHTTPErrorCode, handled.Code)
+	assert.Equal(t, "missing csrf token", handled.Detail)
+}
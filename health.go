@@ -0,0 +1,233 @@
+package xecho
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultHealthCheckTimeout = 5 * time.Second
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusFailed      = "failed"
+	HealthStatusDegraded    = "degraded"
+	HealthStatusUnavailable = "unavailable"
+	HealthStatusDraining    = "draining"
+)
+
+// HealthChecker reports whether a dependency is usable, returning an error if it is not.
+// It should give up when ctx is done.
+type HealthChecker func(ctx context.Context) error
+
+type HealthCheck struct {
+	Name  string
+	Check HealthChecker
+	// Timeout bounds how long Check may run, defaulting to 5 seconds
+	Timeout time.Duration
+	// Critical checks make readiness fail when they fail, others only degrade it
+	Critical bool
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks"`
+}
+
+type HealthCheckResult struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AddHealthCheck registers a dependency check reported by the readiness endpoint. It panics if the
+// check has no name, a name already registered, or no Check, as that is a programming error.
+func (x *Xecho) AddHealthCheck(check HealthCheck) {
+	x.health.add(check)
+}
+
+// Drain makes readiness report unavailable so that load balancers stop sending new traffic
+// before the server shuts down. It is called by Run on a signal, and by Config.DrainRoute.
+// /health also reports 503 while draining, for load balancers that probe it, so liveness probes
+// should use /health/live, which is unaffected. Draining cannot be undone.
+func (x *Xecho) Drain() {
+	x.drain("")
+}
+
+func (x *Xecho) drain(drainedBy string) {
+	if !x.health.drain() {
+		return
+	}
+	logger := x.logger
+	if drainedBy != "" {
+		logger = logger.WithField("drained_by", drainedBy)
+	}
+	logger.Info("Draining: readiness now reports unavailable")
+}
+
+type DrainStatus struct {
+	Draining bool `json:"draining"`
+}
+
+func (x *Xecho) drainStatusHandler(c *Context) error {
+	return c.JSON(http.StatusOK, DrainStatus{Draining: x.IsDraining()})
+}
+
+func (x *Xecho) drainHandler(c *Context) error {
+	user, _ := c.Get(adminUserKey).(string)
+	x.drain(user)
+	return c.JSON(http.StatusOK, DrainStatus{Draining: true})
+}
+
+// IsDraining reports whether Drain has been called
+func (x *Xecho) IsDraining() bool {
+	return x.health.isDraining()
+}
+
+type healthChecks struct {
+	mu       sync.Mutex
+	checks   []HealthCheck
+	cacheTTL time.Duration
+	now      TimeProvider
+	cached   *HealthReport
+	cachedAt time.Time
+	draining int32
+}
+
+func newHealthChecks(cacheTTL time.Duration, now TimeProvider) *healthChecks {
+	return &healthChecks{cacheTTL: cacheTTL, now: now}
+}
+
+func (h *healthChecks) add(check HealthCheck) {
+	if check.Name == "" {
+		panic("xecho: health check name must not be empty")
+	}
+	if check.Check == nil {
+		panic(fmt.Sprintf("xecho: health check %s has no Check", check.Name))
+	}
+	if check.Timeout <= 0 {
+		check.Timeout = defaultHealthCheckTimeout
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range h.checks {
+		if c.Name == check.Name {
+			panic(fmt.Sprintf("xecho: health check %s is already registered", check.Name))
+		}
+	}
+	h.checks = append(h.checks, check)
+	h.cached = nil
+}
+
+// drain returns false if already draining
+func (h *healthChecks) drain() bool {
+	return atomic.CompareAndSwapInt32(&h.draining, 0, 1)
+}
+
+func (h *healthChecks) isDraining() bool {
+	return atomic.LoadInt32(&h.draining) == 1
+}
+
+func (h *healthChecks) healthHandler(c *Context) error {
+	if h.isDraining() {
+		return c.JSONBlob(http.StatusServiceUnavailable, []byte(`{"status": "draining"}`))
+	}
+	return c.JSONBlob(http.StatusOK, []byte(`{"status": "ok"}`))
+}
+
+func (h *healthChecks) livenessHandler(c *Context) error {
+	return c.JSONBlob(http.StatusOK, []byte(`{"status": "ok"}`))
+}
+
+func (h *healthChecks) readinessHandler(c *Context) error {
+	if h.isDraining() {
+		return c.JSON(http.StatusServiceUnavailable, &HealthReport{Status: HealthStatusDraining, Checks: map[string]HealthCheckResult{}})
+	}
+
+	report := h.report()
+	status := http.StatusOK
+	if report.Status == HealthStatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	return c.JSON(status, report)
+}
+
+// report runs every check concurrently, reusing the previous report while it is younger than the cache TTL.
+// The lock is held while checks run so that concurrent readiness probes share a single run.
+func (h *healthChecks) report() *HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && h.now().Sub(h.cachedAt) < h.cacheTTL {
+		return h.cached
+	}
+
+	results := make([]HealthCheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func(i int, check HealthCheck) {
+			defer wg.Done()
+			results[i] = h.run(check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &HealthReport{Status: HealthStatusOK, Checks: map[string]HealthCheckResult{}}
+	for i, check := range h.checks {
+		result := results[i]
+		report.Checks[check.Name] = result
+		if result.Status == HealthStatusOK {
+			continue
+		}
+		if check.Critical {
+			report.Status = HealthStatusUnavailable
+		} else if report.Status == HealthStatusOK {
+			report.Status = HealthStatusDegraded
+		}
+	}
+
+	h.cached = report
+	h.cachedAt = h.now()
+	return report
+}
+
+func (h *healthChecks) run(check HealthCheck) HealthCheckResult {
+	// not derived from the request context as the result is shared with other requests through the cache
+	ctx, cancel := context.WithTimeout(context.Background(), check.Timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	start := h.now()
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("health check panicked: %v", r)
+			}
+		}()
+		done <- check.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("health check timed out after %s", check.Timeout)
+	}
+
+	result := HealthCheckResult{
+		Status:    HealthStatusOK,
+		Critical:  check.Critical,
+		LatencyMs: milliseconds(h.now().Sub(start)),
+	}
+	if err != nil {
+		result.Status = HealthStatusFailed
+		result.Error = err.Error()
+	}
+	return result
+}
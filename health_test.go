@@ -0,0 +1,241 @@
+package xecho
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// readinessReport gets the readiness report, checking that every latency is present and not negative
+// before zeroing it, as it depends on timing
+func readinessReport(t *testing.T, x *Xecho, status int) HealthReport {
+	var report HealthReport
+	apitest.New().
+		Handler(x.Echo).
+		Get("/health/ready").
+		Expect(t).
+		Status(status).
+		Assert(func(res *http.Response, _ *http.Request) error {
+			var raw struct {
+				Checks map[string]map[string]interface{} `json:"checks"`
+			}
+			b, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(b, &raw); err != nil {
+				return err
+			}
+			for name, check := range raw.Checks {
+				latency, ok := check["latency_ms"].(float64)
+				assert.True(t, ok && latency >= 0, "%s should have a latency_ms", name)
+			}
+			return json.Unmarshal(b, &report)
+		}).
+		End()
+	for name, result := range report.Checks {
+		result.LatencyMs = 0
+		report.Checks[name] = result
+	}
+	return report
+}
+
+func TestReadiness_AllChecksPass(t *testing.T) {
+	x := newTestXecho()
+	x.AddHealthCheck(HealthCheck{Name: "db", Critical: true, Check: func(ctx context.Context) error { return nil }})
+	x.AddHealthCheck(HealthCheck{Name: "cache", Check: func(ctx context.Context) error { return nil }})
+
+	report := readinessReport(t, x, http.StatusOK)
+
+	assert.Equal(t, HealthReport{
+		Status: HealthStatusOK,
+		Checks: map[string]HealthCheckResult{
+			"db":    {Status: HealthStatusOK, Critical: true},
+			"cache": {Status: HealthStatusOK},
+		},
+	}, report)
+}
+
+func TestReadiness_NonCriticalFailureDegrades(t *testing.T) {
+	x := newTestXecho()
+	x.AddHealthCheck(HealthCheck{Name: "cache", Check: func(ctx context.Context) error { return errors.New("connection refused") }})
+
+	report := readinessReport(t, x, http.StatusOK)
+
+	assert.Equal(t, HealthReport{
+		Status: HealthStatusDegraded,
+		Checks: map[string]HealthCheckResult{
+			"cache": {Status: HealthStatusFailed, Error: "connection refused"},
+		},
+	}, report)
+}
+
+func TestReadiness_CriticalFailureIsUnavailable(t *testing.T) {
+	x := newTestXecho()
+	x.AddHealthCheck(HealthCheck{Name: "db", Critical: true, Check: func(ctx context.Context) error { return errors.New("timeout") }})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/health/ready").
+		Expect(t).
+		Status(http.StatusServiceUnavailable).
+		End()
+
+	// liveness is unaffected by dependencies
+	apitest.New().
+		Handler(x.Echo).
+		Get("/health/live").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"status": "ok"}`).
+		End()
+}
+
+func TestHealthChecks_TimeoutAndPanic(t *testing.T) {
+	h := newHealthChecks(0, time.Now)
+	h.add(HealthCheck{Name: "slow", Timeout: 10 * time.Millisecond, Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}})
+	h.add(HealthCheck{Name: "broken", Check: func(ctx context.Context) error { panic("boom") }})
+
+	report := h.report()
+
+	assert.Equal(t, "health check timed out after 10ms", report.Checks["slow"].Error)
+	assert.Equal(t, "health check panicked: boom", report.Checks["broken"].Error)
+	assert.Equal(t, HealthStatusDegraded, report.Status)
+}
+
+func TestHealthChecks_RejectsInvalidChecks(t *testing.T) {
+	h := newHealthChecks(0, time.Now)
+	h.add(HealthCheck{Name: "db", Check: func(ctx context.Context) error { return nil }})
+
+	assert.PanicsWithValue(t, "xecho: health check name must not be empty", func() {
+		h.add(HealthCheck{Check: func(ctx context.Context) error { return nil }})
+	})
+	assert.PanicsWithValue(t, "xecho: health check db is already registered", func() {
+		h.add(HealthCheck{Name: "db", Check: func(ctx context.Context) error { return nil }})
+	})
+	assert.PanicsWithValue(t, "xecho: health check cache has no Check", func() {
+		h.add(HealthCheck{Name: "cache"})
+	})
+}
+
+func TestHealthChecks_ResultsAreCached(t *testing.T) {
+	clock := &testClock{now: time.Now()}
+	h := newHealthChecks(time.Second, clock.Now)
+	calls := 0
+	h.add(HealthCheck{Name: "db", Check: func(ctx context.Context) error {
+		calls++
+		return nil
+	}})
+
+	h.report()
+	clock.Add(500 * time.Millisecond)
+	h.report()
+	assert.Equal(t, 1, calls)
+
+	clock.Add(time.Second)
+	h.report()
+	assert.Equal(t, 2, calls)
+}
+
+func TestDrain_ReadinessFailsAndLivenessStaysUp(t *testing.T) {
+	x := newTestXecho()
+	assert.False(t, x.IsDraining())
+
+	x.Drain()
+
+	assert.True(t, x.IsDraining())
+	apitest.New().
+		Handler(x.Echo).
+		Get("/health/ready").
+		Expect(t).
+		Status(http.StatusServiceUnavailable).
+		Body(`{"status": "draining", "checks": {}}`).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Get("/health").
+		Expect(t).
+		Status(http.StatusServiceUnavailable).
+		Body(`{"status": "draining"}`).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Get("/health/live").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+func TestDrainRoute(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.RoutePrefix = "/acme"
+	conf.DrainRoute = "/admin/drain"
+	conf.AdminAuthenticator = AdminTokenAuthenticator(map[string]string{"ann": "secret-1"})
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/acme/admin/drain").
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		End()
+	assert.False(t, x.IsDraining())
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/acme/admin/drain").
+		Header("Authorization", "Bearer secret-1").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"draining": false}`).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Post("/acme/admin/drain").
+		Header("Authorization", "Bearer secret-1").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"draining": true}`).
+		End()
+
+	assert.True(t, x.IsDraining())
+	assert.Contains(t, buffer.String(), `"drained_by":"ann"`)
+	apitest.New().
+		Handler(x.Echo).
+		Get("/acme/health/ready").
+		Expect(t).
+		Status(http.StatusServiceUnavailable).
+		End()
+}
@@ -1,9 +1,7 @@
 package xecho
 
 import (
-	"github.com/newrelic/go-agent"
 	"net/http"
-	"net/http/httputil"
 	"time"
 )
 
@@ -15,10 +13,13 @@ type loggingTransport struct {
 
 // Wraps the outbound request round trip with logging and metrics
 func (t *loggingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
-	segment := newrelic.StartExternalSegment(t.inboundContext.NewRelicTx, r)
+	segment := startExternalSegment(t.inboundContext.Transaction, r)
 	logger := t.inboundContext.Logger().(*Logger)
+	if fieldsSegment, ok := segment.(TransactionLogFields); ok {
+		logger = &Logger{logger.WithFields(t.inboundContext.logSchema.traceFields(fieldsSegment.LogFields()))}
+	}
 
-	if err := debugDumpRequest(r, logger, t.isDebug); err != nil {
+	if err := debugDumpRequest(r, logger, t.isDebug, t.inboundContext.dumpConfig()); err != nil {
 		return nil, err
 	}
 
@@ -26,25 +27,34 @@ func (t *loggingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 
 	res, err := t.transport.RoundTrip(r)
 
-	segment.Response = res
-	_ = segment.End()
+	_ = segment.End(res)
+
+	reqTime := time.Now().Sub(startTime)
+	if t.inboundContext.metrics != nil {
+		t.inboundContext.metrics.observeClientRequest(r, res, reqTime)
+	}
 
 	if err != nil {
 		logger.Errorf("Failed to get response in outbound request: %s %s", r.Method, r.URL.String())
 		return nil, err
 	}
 
-	reqTime := time.Now().Sub(startTime)
-
 	logger.Infof("Outgoing request: %s %s %d (%fs)", r.Method, r.URL.String(), res.StatusCode, reqTime.Seconds())
 
-	if err := debugDumpResponse(res, logger, t.isDebug); err != nil {
+	if err := debugDumpResponse(res, logger, t.isDebug, t.inboundContext.dumpConfig()); err != nil {
 		return nil, err
 	}
 
 	return res, nil
 }
 
+func startExternalSegment(tx Transaction, r *http.Request) ExternalSegment {
+	if tx == nil {
+		return noopExternalSegment{}
+	}
+	return tx.StartExternalSegment(r)
+}
+
 func NewHttpClient(
 	context *Context,
 	isDebug bool,
@@ -57,30 +67,30 @@ func NewHttpClient(
 	return &http.Client{Transport: loggingTransport}
 }
 
-func debugDumpRequest(r *http.Request, logger *Logger, isDebug bool) error {
+func debugDumpRequest(r *http.Request, logger *Logger, isDebug bool, dump DumpConfig) error {
 	if !isDebug {
 		return nil
 	}
 
-	reqDump, err := httputil.DumpRequest(r, true)
+	reqDump, err := dump.dumpRequest(r)
 	if err != nil {
 		return err
 	}
 
-	logger.Debugf("%s", string(reqDump))
+	logger.Debugf("%s", reqDump)
 	return nil
 }
 
-func debugDumpResponse(res *http.Response, logger *Logger, isDebug bool) error {
+func debugDumpResponse(res *http.Response, logger *Logger, isDebug bool, dump DumpConfig) error {
 	if !isDebug {
 		return nil
 	}
 
-	resDump, err := httputil.DumpResponse(res, true)
+	resDump, err := dump.dumpResponse(res, true)
 	if err != nil {
 		return err
 	}
 
-	logger.Debugf("%s", string(resDump))
+	logger.Debugf("%s", resDump)
 	return nil
 }
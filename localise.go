@@ -0,0 +1,134 @@
+package xecho
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/labstack/echo"
+)
+
+const (
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+// MessageBundle holds error details translated into each locale, keyed by error code.
+// Messages are text/template templates executed with the public Error.Params, e.g. "Order {{.order}}
+// not found", so that params kept from clients, such as the reason, can't be shown in a detail.
+type MessageBundle struct {
+	mu            sync.RWMutex
+	defaultLocale string
+	messages      map[string]map[string]*template.Template
+}
+
+// NewMessageBundle creates a bundle that falls back to defaultLocale when the client accepts
+// none of the locales an error has been translated into
+func NewMessageBundle(defaultLocale string) *MessageBundle {
+	return &MessageBundle{
+		defaultLocale: normaliseLocale(defaultLocale),
+		messages:      map[string]map[string]*template.Template{},
+	}
+}
+
+// Add registers the message for an error code in a locale such as "en" or "cy-GB"
+func (b *MessageBundle) Add(locale, code, message string) error {
+	tmpl, err := template.New(code).Option("missingkey=zero").Parse(message)
+	if err != nil {
+		return fmt.Errorf("xecho: invalid message for %s in %s: %s", code, locale, err)
+	}
+
+	locale = normaliseLocale(locale)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.messages[locale] == nil {
+		b.messages[locale] = map[string]*template.Template{}
+	}
+	b.messages[locale][code] = tmpl
+	return nil
+}
+
+// Localise returns a copy of err with its detail in the best locale for acceptLanguage, the value
+// of an Accept-Language header, along with that locale. err is returned unchanged with an empty
+// locale if it has no message in an accepted locale or the default one.
+func (b *MessageBundle) Localise(err *Error, acceptLanguage string) (*Error, string) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, locale := range append(acceptedLocales(acceptLanguage), b.defaultLocale) {
+		tmpl, ok := b.messages[locale][err.Code]
+		if !ok {
+			continue
+		}
+		params := err.publicParams()
+		if params == nil {
+			params = map[string]string{}
+		}
+		detail := &strings.Builder{}
+		if tmpl.Execute(detail, params) != nil {
+			return err, ""
+		}
+		return err.WithDetail(detail.String()), locale
+	}
+	return err, ""
+}
+
+// LocalisedErrorHandler translates error details with messages, according to the request's
+// Accept-Language header, before handing the error to next
+func LocalisedErrorHandler(messages *MessageBundle, next ErrorHandlerFunc) ErrorHandlerFunc {
+	return func(c *Context, err *Error) {
+		localised, locale := messages.Localise(err, c.Request().Header.Get(headerAcceptLanguage))
+		// the body depends on Accept-Language, so caches must key on it
+		c.Response().Header().Add(echo.HeaderVary, headerAcceptLanguage)
+		if locale != "" {
+			c.Response().Header().Set(headerContentLanguage, locale)
+		}
+		next(c, localised)
+	}
+}
+
+// acceptedLocales parses an Accept-Language header into locales in order of preference. A regional
+// locale such as cy-GB is followed by its language, cy, so that it matches either.
+func acceptedLocales(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+	var accepted []weighted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		locale := normaliseLocale(fields[0])
+		if locale == "" || locale == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			accepted = append(accepted, weighted{locale, q})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool { return accepted[i].q > accepted[j].q })
+
+	locales := make([]string, 0, len(accepted)*2)
+	for _, a := range accepted {
+		locales = append(locales, a.locale)
+		if i := strings.Index(a.locale, "-"); i > 0 {
+			locales = append(locales, a.locale[:i])
+		}
+	}
+	return locales
+}
+
+func normaliseLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
@@ -0,0 +1,77 @@
+package xecho
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageBundle_Localise(t *testing.T) {
+	b := NewMessageBundle("en")
+	assert.NoError(t, b.Add("en", "NOT_FOUND", "Order {{.order}} not found"))
+	assert.NoError(t, b.Add("cy", "NOT_FOUND", "Heb ddod o hyd i archeb {{.order}}"))
+	err := ErrNotFound.WithPublicParam("order", "123")
+
+	tests := []struct {
+		acceptLanguage string
+		detail         string
+		locale         string
+	}{
+		{"cy", "Heb ddod o hyd i archeb 123", "cy"},
+		{"cy-GB", "Heb ddod o hyd i archeb 123", "cy"},
+		{"fr, en;q=0.5, cy;q=0.8", "Heb ddod o hyd i archeb 123", "cy"},
+		{"fr", "Order 123 not found", "en"},
+		{"", "Order 123 not found", "en"},
+	}
+	for _, test := range tests {
+		localised, locale := b.Localise(err, test.acceptLanguage)
+		assert.Equal(t, test.detail, localised.Detail, test.acceptLanguage)
+		assert.Equal(t, test.locale, locale, test.acceptLanguage)
+	}
+	assert.Equal(t, "Not found", err.Detail)
+}
+
+func TestMessageBundle_OnlyPublicParamsAreShown(t *testing.T) {
+	b := NewMessageBundle("en")
+	assert.NoError(t, b.Add("en", "NOT_FOUND", "Order {{.order}} not found{{.reason}}"))
+
+	localised, _ := b.Localise(ErrNotFound.Wrap(errors.New("db timeout")).WithPublicParam("order", "123"), "en")
+	assert.Equal(t, "Order 123 not found", localised.Detail)
+
+	localised, _ = b.Localise(ErrNotFound.Wrap(errors.New("db timeout")), "en")
+	assert.Equal(t, "Order  not found", localised.Detail)
+}
+
+func TestMessageBundle_UntranslatedErrorUnchanged(t *testing.T) {
+	b := NewMessageBundle("en")
+
+	err := ErrConflict.New()
+	localised, locale := b.Localise(err, "cy")
+
+	assert.Same(t, err, localised)
+	assert.Empty(t, locale)
+	assert.Error(t, b.Add("en", "CONFLICT", "{{.broken"))
+}
+
+func TestLocalisedErrorHandler(t *testing.T) {
+	b := NewMessageBundle("en")
+	assert.NoError(t, b.Add("cy", "NOT_FOUND", "Heb ei ganfod"))
+	conf := testConfig()
+	conf.ErrorMessages = b
+	x := New(conf)
+	x.GET("/orders", func(c *Context) error { return ErrNotFound })
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/orders").
+		Header("Accept-Language", "cy-GB").
+		Expect(t).
+		Status(http.StatusNotFound).
+		Header("Content-Language", "cy").
+		Header("Vary", "Accept-Language").
+		Body(`{"code": "NOT_FOUND", "detail": "Heb ei ganfod"}`).
+		End()
+}
@@ -0,0 +1,213 @@
+package xecho
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const adminUserKey = "xecho.admin_user"
+
+// AdminAuthenticator identifies the user making a request to an admin route, returning false if
+// the request is not allowed
+type AdminAuthenticator func(c *Context) (user string, ok bool)
+
+// AdminTokenAuthenticator allows requests with an "Authorization: Bearer <token>" header holding
+// one of the tokens, which are keyed by the name of the user they identify
+func AdminTokenAuthenticator(tokens map[string]string) AdminAuthenticator {
+	return func(c *Context) (string, bool) {
+		auth := c.Request().Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return "", false
+		}
+		token := []byte(strings.TrimPrefix(auth, "Bearer "))
+		for user, t := range tokens {
+			if t != "" && subtle.ConstantTimeCompare(token, []byte(t)) == 1 {
+				return user, true
+			}
+		}
+		return "", false
+	}
+}
+
+func requireAdmin(authenticate AdminAuthenticator) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) error {
+			user, ok := authenticate(c)
+			if !ok {
+				return ErrUnauthorised
+			}
+			c.Set(adminUserKey, user)
+			return next(c)
+		}
+	}
+}
+
+type LogLevelStatus struct {
+	Level        string `json:"level"`
+	DefaultLevel string `json:"default_level"`
+	// ChangedBy is empty when the level is the default
+	ChangedBy string     `json:"changed_by,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// SetLogLevel changes the level of every logger at runtime, reverting to Config.LogLevel after
+// expiry unless it is 0. changedBy is logged with the change.
+func (x *Xecho) SetLogLevel(level logrus.Level, expiry time.Duration, changedBy string) {
+	x.logLevels.set(level, expiry, changedBy)
+}
+
+// LogLevel reports the current log level and who changed it
+func (x *Xecho) LogLevel() LogLevelStatus {
+	return x.logLevels.status()
+}
+
+type logLevels struct {
+	mu           sync.Mutex
+	logger       *logrus.Entry
+	defaultLevel logrus.Level
+	now          TimeProvider
+	changedBy    string
+	expiresAt    time.Time
+	timer        *time.Timer
+	// changes counts calls to set, so that the timer of a replaced change does nothing
+	changes int
+}
+
+func newLogLevels(logger *logrus.Entry, defaultLevel logrus.Level, now TimeProvider) *logLevels {
+	return &logLevels{logger: logger, defaultLevel: defaultLevel, now: now}
+}
+
+func (l *logLevels) set(level logrus.Level, expiry time.Duration, changedBy string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+	l.changes++
+	l.changedBy = changedBy
+	l.expiresAt = time.Time{}
+	if expiry > 0 {
+		l.expiresAt = l.now().Add(expiry)
+		change := l.changes
+		l.timer = time.AfterFunc(expiry, func() { l.revert(change) })
+	}
+	l.change(level, changedBy)
+}
+
+func (l *logLevels) revert(change int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if change != l.changes {
+		return
+	}
+	l.changedBy = ""
+	l.expiresAt = time.Time{}
+	l.timer = nil
+	l.change(l.defaultLevel, "expiry")
+}
+
+// change is logged at warning level, so that it shows at the usual levels before and after, and
+// while the more verbose of the two levels is set, so that raising the level to error still logs it
+func (l *logLevels) change(level logrus.Level, changedBy string) {
+	previous := l.logger.Logger.GetLevel()
+	fields := logrus.Fields{
+		"log_level":          level.String(),
+		"previous_log_level": previous.String(),
+		"changed_by":         changedBy,
+	}
+	if !l.expiresAt.IsZero() {
+		fields["expires_at"] = l.expiresAt
+	}
+	logLevel := logrus.WarnLevel
+	if verbose := maxLevel(previous, level); verbose < logLevel {
+		// neither level logs warnings, e.g. error to fatal
+		logLevel = verbose
+	}
+	if logLevel < logrus.FatalLevel {
+		// logging at panic level panics, while Logf at fatal level doesn't exit
+		logLevel = logrus.FatalLevel
+	}
+	logChange := func() {
+		l.logger.WithFields(fields).Logf(logLevel, "Log level changed from %s to %s by %s", previous, level, changedBy)
+	}
+	if level < previous {
+		logChange()
+		l.logger.Logger.SetLevel(level)
+	} else {
+		l.logger.Logger.SetLevel(level)
+		logChange()
+	}
+}
+
+// maxLevel returns the more verbose of a and b
+func maxLevel(a, b logrus.Level) logrus.Level {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// stop cancels the revert of a change with an expiry
+func (l *logLevels) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.timer != nil {
+		l.timer.Stop()
+		l.timer = nil
+	}
+}
+
+func (l *logLevels) status() LogLevelStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := LogLevelStatus{
+		Level:        l.logger.Logger.GetLevel().String(),
+		DefaultLevel: l.defaultLevel.String(),
+		ChangedBy:    l.changedBy,
+	}
+	if !l.expiresAt.IsZero() {
+		expiresAt := l.expiresAt
+		status.ExpiresAt = &expiresAt
+	}
+	return status
+}
+
+type logLevelRequest struct {
+	Level string `json:"level" validate:"required,oneof=trace debug info warn warning error fatal panic"`
+	// Expiry is a duration such as 15m, after which the level reverts to the default
+	Expiry string `json:"expiry"`
+}
+
+func (l *logLevels) getHandler(c *Context) error {
+	return c.JSON(http.StatusOK, l.status())
+}
+
+func (l *logLevels) putHandler(c *Context, req *logLevelRequest) error {
+	level, err := logrus.ParseLevel(req.Level)
+	if err != nil {
+		return err
+	}
+	var expiry time.Duration
+	if req.Expiry != "" {
+		if expiry, err = time.ParseDuration(req.Expiry); err != nil || expiry < 0 {
+			return ErrValidationFailed.WithViolations(FieldViolation{
+				Field:    "expiry",
+				Location: "body",
+				Message:  "must be a positive duration such as 15m",
+			})
+		}
+	}
+	user, _ := c.Get(adminUserKey).(string)
+	l.set(level, expiry, user)
+	return c.JSON(http.StatusOK, l.status())
+}
@@ -0,0 +1,146 @@
+package xecho
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func logLevelTestXecho(buffer *bytes.Buffer) *Xecho {
+	conf := testConfig()
+	conf.RoutePrefix = "/acme"
+	conf.LogLevelRoute = "/admin/log-level"
+	conf.AdminAuthenticator = AdminTokenAuthenticator(map[string]string{"ann": "secret-1"})
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	return x
+}
+
+func TestLogLevelRoute(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logLevelTestXecho(buffer)
+	x.logLevels.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	apitest.New().
+		Handler(x.Echo).
+		Put("/acme/admin/log-level").
+		JSON(`{"level": "debug"}`).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Put("/acme/admin/log-level").
+		Header("Authorization", "Bearer wrong").
+		JSON(`{"level": "debug"}`).
+		Expect(t).
+		Status(http.StatusUnauthorized).
+		End()
+	assert.Equal(t, logrus.InfoLevel, x.logger.Logger.GetLevel())
+
+	apitest.New().
+		Handler(x.Echo).
+		Put("/acme/admin/log-level").
+		Header("Authorization", "Bearer secret-1").
+		JSON(`{"level": "debug", "expiry": "15m"}`).
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"level": "debug", "default_level": "info", "changed_by": "ann", "expires_at": "2026-01-02T03:19:05Z"}`).
+		End()
+
+	assert.Equal(t, logrus.DebugLevel, x.logger.Logger.GetLevel())
+	assert.Contains(t, buffer.String(), `"changed_by":"ann"`)
+	assert.Contains(t, buffer.String(), `"msg":"Log level changed from info to debug by ann"`)
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/acme/admin/log-level").
+		Header("Authorization", "Bearer secret-1").
+		Expect(t).
+		Status(http.StatusOK).
+		Body(`{"level": "debug", "default_level": "info", "changed_by": "ann", "expires_at": "2026-01-02T03:19:05Z"}`).
+		End()
+	x.SetLogLevel(logrus.InfoLevel, 0, "test")
+}
+
+func TestLogLevelRoute_RejectsInvalidRequests(t *testing.T) {
+	x := logLevelTestXecho(&bytes.Buffer{})
+
+	apitest.New().
+		Handler(x.Echo).
+		Put("/acme/admin/log-level").
+		Header("Authorization", "Bearer secret-1").
+		JSON(`{"level": "loud", "expiry": "soon"}`).
+		Expect(t).
+		Status(http.StatusUnprocessableEntity).
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Put("/acme/admin/log-level").
+		Header("Authorization", "Bearer secret-1").
+		JSON(`{"level": "warn", "expiry": "soon"}`).
+		Expect(t).
+		Status(http.StatusUnprocessableEntity).
+		Body(`{"code": "VALIDATION_FAILED", "detail": "Validation failed",
+			"violations": [{"field": "expiry", "location": "body", "message": "must be a positive duration such as 15m"}]}`).
+		End()
+	assert.Equal(t, logrus.InfoLevel, x.logger.Logger.GetLevel())
+}
+
+func TestXecho_SetLogLevelReverts(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logLevelTestXecho(buffer)
+
+	x.SetLogLevel(logrus.DebugLevel, time.Hour, "ann")
+	x.SetLogLevel(logrus.WarnLevel, 10*time.Millisecond, "bob")
+
+	assert.Equal(t, "bob", x.LogLevel().ChangedBy)
+	assert.Eventually(t, func() bool { return x.LogLevel().Level == "info" }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, LogLevelStatus{Level: "info", DefaultLevel: "info"}, x.LogLevel())
+	assert.Contains(t, buffer.String(), `"msg":"Log level changed from warning to info by expiry"`)
+}
+
+func TestXecho_SetLogLevelLogsRaisingToError(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logLevelTestXecho(buffer)
+
+	x.SetLogLevel(logrus.ErrorLevel, 0, "ann")
+	x.SetLogLevel(logrus.FatalLevel, 0, "bob")
+
+	assert.Contains(t, buffer.String(), `"level":"warning","log_level":"error"`)
+	assert.Contains(t, buffer.String(), `"msg":"Log level changed from info to error by ann"`)
+	assert.Contains(t, buffer.String(), `"msg":"Log level changed from error to fatal by bob"`)
+	x.SetLogLevel(logrus.InfoLevel, 0, "test")
+}
+
+func TestLogLevelRoute_SettingPanicTwice(t *testing.T) {
+	x := logLevelTestXecho(&bytes.Buffer{})
+
+	for i := 0; i < 2; i++ {
+		apitest.New().
+			Handler(x.Echo).
+			Put("/acme/admin/log-level").
+			Header("Authorization", "Bearer secret-1").
+			JSON(`{"level": "panic"}`).
+			Expect(t).
+			Status(http.StatusOK).
+			End()
+	}
+	assert.Equal(t, logrus.PanicLevel, x.logger.Logger.GetLevel())
+}
+
+func TestXecho_ShutdownStopsLogLevelRevert(t *testing.T) {
+	x := logLevelTestXecho(&bytes.Buffer{})
+	x.SetLogLevel(logrus.DebugLevel, 10*time.Millisecond, "ann")
+
+	assert.NoError(t, x.Shutdown(context.Background()))
+	time.Sleep(30 * time.Millisecond)
+
+	assert.Equal(t, logrus.DebugLevel, x.logger.Logger.GetLevel(), "the level should not revert after shutdown")
+}
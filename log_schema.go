@@ -0,0 +1,323 @@
+package xecho
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+)
+
+// LogSchema selects the field names used in logs, so that log pipelines needn't remap them
+type LogSchema string
+
+const (
+	// LogSchemaXecho is the default xecho field layout
+	LogSchemaXecho LogSchema = "xecho"
+	// LogSchemaECS uses Elastic Common Schema field names
+	LogSchemaECS LogSchema = "ecs"
+	// LogSchemaGCP uses the Google Cloud structured logging fields, with an httpRequest in access logs
+	LogSchemaGCP LogSchema = "gcp"
+	// LogSchemaCombined writes access logs as Apache Combined Log Format lines. Other log lines keep
+	// the xecho fields. The format has no place for bodies or the sample rate, so it can't be used
+	// with body logging or sampling.
+	LogSchemaCombined LogSchema = "combined"
+)
+
+var logSchemas = []LogSchema{LogSchemaXecho, LogSchemaECS, LogSchemaGCP, LogSchemaCombined}
+
+func (s LogSchema) valid() bool {
+	for _, schema := range logSchemas {
+		if s == schema {
+			return true
+		}
+	}
+	return false
+}
+
+// formatter renames the time, level and message keys of a JSON formatter without its own FieldMap,
+// or for LogSchemaCombined writes access log entries as Combined Log Format lines
+func (s LogSchema) formatter(formatter logrus.Formatter) logrus.Formatter {
+	if s == LogSchemaCombined {
+		return &combinedFormatter{next: formatter}
+	}
+	jsonFormatter, ok := formatter.(*logrus.JSONFormatter)
+	if !ok || jsonFormatter.FieldMap != nil {
+		return formatter
+	}
+	renamed := *jsonFormatter
+	switch s {
+	case LogSchemaECS:
+		renamed.FieldMap = logrus.FieldMap{
+			logrus.FieldKeyTime:  "@timestamp",
+			logrus.FieldKeyLevel: "log.level",
+			logrus.FieldKeyMsg:   "message",
+		}
+	case LogSchemaGCP:
+		renamed.FieldMap = logrus.FieldMap{
+			logrus.FieldKeyLevel: "severity",
+			logrus.FieldKeyMsg:   "message",
+		}
+	default:
+		return formatter
+	}
+	return &renamed
+}
+
+// appFields are the fields added to every log line by the app logger
+func (s LogSchema) appFields(conf Config) logrus.Fields {
+	serviceName := getServiceName(conf.ProjectName, conf.AppName, conf.EnvName)
+	switch s {
+	case LogSchemaECS:
+		return logrus.Fields{
+			"service.name":        serviceName,
+			"service.version":     conf.BuildVersion,
+			"service.environment": conf.EnvName,
+			"host.hostname":       getHostName(),
+			"labels.project":      conf.ProjectName,
+			"labels.application":  conf.AppName,
+		}
+	case LogSchemaGCP:
+		return logrus.Fields{
+			"serviceContext": map[string]string{"service": serviceName, "version": conf.BuildVersion},
+			"logging.googleapis.com/labels": map[string]string{
+				"project":     conf.ProjectName,
+				"application": conf.AppName,
+				"environment": conf.EnvName,
+				"hostname":    getHostName(),
+			},
+		}
+	}
+	return logrus.Fields{
+		"service_name":  serviceName,
+		"project":       conf.ProjectName,
+		"application":   conf.AppName,
+		"environment":   conf.EnvName,
+		"build_version": conf.BuildVersion,
+		"hostname":      getHostName(),
+	}
+}
+
+// requestFields are the fields added to every log line by the request-scoped logger
+func (s LogSchema) requestFields(
+	r *http.Request,
+	route string,
+	ip string,
+	correlationID string,
+	headers logrus.Fields,
+) logrus.Fields {
+	switch s {
+	case LogSchemaECS:
+		return logrus.Fields{
+			"http.request.id":      correlationID,
+			"url.original":         requestURI(r),
+			"url.domain":           r.Host,
+			"labels.route":         route,
+			"source.address":       r.RemoteAddr,
+			"http.request.method":  r.Method,
+			"client.ip":            ip,
+			"http.request.headers": headers,
+		}
+	case LogSchemaGCP:
+		// not labels, as those would replace the app logger's labels rather than add to them
+		return logrus.Fields{
+			"logging.googleapis.com/operation": map[string]string{"id": correlationID},
+			"request": logrus.Fields{
+				"url":         requestURI(r),
+				"route":       route,
+				"remote_addr": r.RemoteAddr,
+				"method":      r.Method,
+				"ip":          ip,
+				"headers":     headers,
+			},
+		}
+	}
+	headers["host"] = r.Host
+	return logrus.Fields{
+		"correlation_id": correlationID,
+		"url":            requestURI(r),
+		"route":          route,
+		"remote_addr":    r.RemoteAddr,
+		"method":         r.Method,
+		"ip":             ip,
+		"headers":        headers,
+	}
+}
+
+// accessLogFields remaps the xecho access log request and response maps to the schema
+func (s LogSchema) accessLogFields(
+	c echo.Context,
+	timeTaken time.Duration,
+	statusCode int,
+	err error,
+	request logrus.Fields,
+	response logrus.Fields,
+) logrus.Fields {
+	r := c.Request()
+	var fields logrus.Fields
+	switch s {
+	case LogSchemaECS:
+		fields = logrus.Fields{
+			"event.duration":            timeTaken.Nanoseconds(),
+			"http.request.method":       r.Method,
+			"url.domain":                r.Host,
+			"url.query":                 r.URL.RawQuery,
+			"http.request.body.bytes":   request["Content-length"],
+			"http.response.status_code": statusCode,
+			"http.response.body.bytes":  response["content_length"],
+		}
+		addECSBody(fields, "http.request", request)
+		addECSBody(fields, "http.response", response)
+		fields["http.request.headers"] = request["headers"]
+		if h, ok := response["headers"]; ok {
+			fields["http.response.headers"] = h
+		}
+		if err != nil {
+			fields["error.message"] = err.Error()
+		}
+		return fields
+	case LogSchemaGCP:
+		fields = logrus.Fields{
+			"httpRequest": logrus.Fields{
+				"requestMethod": r.Method,
+				"requestUrl":    requestURI(r),
+				"requestSize":   strconv.FormatInt(int64(math.Max(float64(r.ContentLength), 0)), 10),
+				"status":        statusCode,
+				"responseSize":  strconv.FormatInt(int64(math.Max(float64(c.Response().Size), 0)), 10),
+				"userAgent":     r.UserAgent(),
+				"remoteIp":      c.RealIP(),
+				"referer":       r.Referer(),
+				"latency":       fmt.Sprintf("%.9fs", timeTaken.Seconds()),
+				"protocol":      r.Proto,
+			},
+		}
+		delete(request, "method")
+		delete(request, "host_name")
+		delete(request, "Content-length")
+		delete(response, "status_code")
+		delete(response, "content_length")
+		request["query_params"] = c.QueryParams()
+		// replaces the request field of the request-scoped logger
+		request["route"] = c.Path()
+		fields["request"] = request
+		if len(response) > 0 {
+			fields["response"] = response
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		return fields
+	}
+	fields = logrus.Fields{
+		"duration_ms": milliseconds(timeTaken),
+		"request":     request,
+		"response":    response,
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	return fields
+}
+
+// traceFields renames the trace and span IDs of a tracer's log fields, trace_id and span_id from
+// OpenTelemetry or trace.id and span.id from New Relic, to those of the schema
+func (s LogSchema) traceFields(fields logrus.Fields) logrus.Fields {
+	var traceKey, spanKey string
+	switch s {
+	case LogSchemaECS:
+		traceKey, spanKey = "trace.id", "span.id"
+	case LogSchemaGCP:
+		traceKey, spanKey = "logging.googleapis.com/trace", "logging.googleapis.com/spanId"
+	default:
+		return fields
+	}
+	renamed := logrus.Fields{}
+	for k, v := range fields {
+		switch k {
+		case "trace_id", "trace.id":
+			k = traceKey
+		case "span_id", "span.id":
+			k = spanKey
+		}
+		renamed[k] = v
+	}
+	return renamed
+}
+
+// addECSBody adds a logged body as prefix.body.content, which ECS requires to be a string
+func addECSBody(fields logrus.Fields, prefix string, m logrus.Fields) {
+	if body, ok := m["body"]; ok {
+		if s, ok := body.(string); ok {
+			fields[prefix+".body.content"] = s
+		} else if b, err := json.Marshal(body); err == nil {
+			fields[prefix+".body.content"] = string(b)
+		}
+	}
+	if truncated, ok := m["body_truncated"]; ok {
+		fields[prefix+".body.truncated"] = truncated
+	}
+}
+
+// requestURI is the request target as sent by the client, which is only set on server requests
+func requestURI(r *http.Request) string {
+	if r.RequestURI != "" {
+		return r.RequestURI
+	}
+	return r.URL.RequestURI()
+}
+
+// combinedLineField holds the Combined Log Format line of an access log entry
+const combinedLineField = "combined_log_line"
+
+// combinedFormatter writes entries with a combinedLineField as that line, and others with next
+type combinedFormatter struct {
+	next logrus.Formatter
+}
+
+func (f *combinedFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if line, ok := entry.Data[combinedLineField].(string); ok {
+		return []byte(line + "\n"), nil
+	}
+	return f.next.Format(entry)
+}
+
+// combinedLogLine formats a request in the Apache Combined Log Format
+func combinedLogLine(c echo.Context, statusCode int, start time.Time) string {
+	r := c.Request()
+	size := "-"
+	if c.Response().Size > 0 {
+		size = strconv.FormatInt(c.Response().Size, 10)
+	}
+	return fmt.Sprintf(`%s - %s [%s] "%s %s %s" %d %s "%s" "%s"`,
+		c.RealIP(),
+		combinedUser(r),
+		start.Format("02/Jan/2006:15:04:05 -0700"),
+		combinedQuote(r.Method),
+		combinedQuote(requestURI(r)),
+		r.Proto,
+		statusCode,
+		size,
+		combinedQuote(r.Referer()),
+		combinedQuote(r.UserAgent()),
+	)
+}
+
+func combinedUser(r *http.Request) string {
+	if user, _, ok := r.BasicAuth(); ok && user != "" {
+		return combinedQuote(user)
+	}
+	return "-"
+}
+
+// combinedQuote escapes s for a quoted field, using - for empty values as Apache does
+func combinedQuote(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
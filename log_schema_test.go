@@ -0,0 +1,211 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func logSchemaTestXecho(schema LogSchema, buffer *bytes.Buffer) *Xecho {
+	conf := testConfig()
+	conf.LogSchema = schema
+	if schema != LogSchemaCombined {
+		conf.AccessLogBodies.Routes = []string{"/orders"}
+	}
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.POST("/orders", func(c *Context) error {
+		c.Logger().Info("creating order")
+		return c.JSON(http.StatusCreated, map[string]int{"id": 1})
+	})
+	return x
+}
+
+func logSchemaTestRequest(t *testing.T, x *Xecho) []map[string]interface{} {
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		Query("source", "web").
+		Header("Correlation-Id", "abc-123").
+		Header("User-Agent", "test-agent").
+		JSON(`{"sku":"123"}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(x.logger.Logger.Out.(*bytes.Buffer).String()), "\n") {
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields), line)
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestLogSchema_ECS(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logSchemaTestXecho(LogSchemaECS, buffer)
+	buffer.Reset()
+
+	lines := logSchemaTestRequest(t, x)
+
+	assert.Len(t, lines, 2)
+	handlerLine, accessLine := lines[0], lines[1]
+	assert.Equal(t, "creating order", handlerLine["message"])
+	assert.Equal(t, "info", handlerLine["log.level"])
+	assert.Contains(t, handlerLine, "@timestamp")
+	assert.Equal(t, "acme-login-dev", handlerLine["service.name"])
+	assert.Equal(t, "abc-123", handlerLine["http.request.id"])
+	assert.Equal(t, "/orders", handlerLine["labels.route"])
+	assert.Equal(t, "POST", handlerLine["http.request.method"])
+	assert.NotContains(t, handlerLine, "correlation_id")
+
+	assert.Equal(t, "[POST] /orders 201", accessLine["message"])
+	assert.Equal(t, float64(201), accessLine["http.response.status_code"])
+	assert.Equal(t, "source=web", accessLine["url.query"])
+	assert.Equal(t, `{"sku":"123"}`, accessLine["http.request.body.content"])
+	assert.Equal(t, `{"id":1}`, accessLine["http.response.body.content"])
+	assert.Equal(t, "test-agent", accessLine["http.request.headers"].(map[string]interface{})["user-agent"])
+	assert.Contains(t, accessLine, "event.duration")
+	assert.NotContains(t, accessLine, "duration_ms")
+	assert.NotContains(t, accessLine, "response")
+}
+
+func TestLogSchema_GCP(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logSchemaTestXecho(LogSchemaGCP, buffer)
+	buffer.Reset()
+
+	lines := logSchemaTestRequest(t, x)
+
+	assert.Len(t, lines, 2)
+	handlerLine, accessLine := lines[0], lines[1]
+	assert.Equal(t, "creating order", handlerLine["message"])
+	assert.Equal(t, "info", handlerLine["severity"])
+	assert.Equal(t, map[string]interface{}{"service": "acme-login-dev", "version": ""}, handlerLine["serviceContext"])
+	assert.Equal(t, map[string]interface{}{"id": "abc-123"}, handlerLine["logging.googleapis.com/operation"])
+	assert.Equal(t, "/orders", handlerLine["request"].(map[string]interface{})["route"])
+	assert.NotContains(t, handlerLine, "httpRequest")
+
+	httpRequest := accessLine["httpRequest"].(map[string]interface{})
+	assert.Equal(t, "POST", httpRequest["requestMethod"])
+	assert.Equal(t, "/orders?source=web", httpRequest["requestUrl"])
+	assert.Equal(t, float64(201), httpRequest["status"])
+	assert.Equal(t, "13", httpRequest["requestSize"])
+	assert.Equal(t, "9", httpRequest["responseSize"])
+	assert.Equal(t, "test-agent", httpRequest["userAgent"])
+	assert.Regexp(t, `^\d+\.\d{9}s$`, httpRequest["latency"])
+	assert.Equal(t, map[string]interface{}{"sku": "123"}, accessLine["request"].(map[string]interface{})["body"])
+	assert.Equal(t, "/orders", accessLine["request"].(map[string]interface{})["route"])
+	assert.Equal(t, map[string]interface{}{"id": float64(1)}, accessLine["response"].(map[string]interface{})["body"])
+}
+
+func TestLogSchema_Combined(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logSchemaTestXecho(LogSchemaCombined, buffer)
+	buffer.Reset()
+	hook := test.NewLocal(x.logger.Logger)
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		Query("source", "web").
+		Header("Referer", "https://example.com/").
+		Header("User-Agent", `test "agent"`).
+		Header("X-Real-Ip", "10.0.0.1").
+		JSON(`{"sku":"123"}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"msg":"creating order"`, "other lines should keep the xecho fields")
+	assert.Regexp(t, regexp.MustCompile(
+		`^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "POST /orders\?source=web HTTP/1\.1" 201 9 `+
+			`"https://example\.com/" "test \\"agent\\""$`), lines[1])
+	assert.Equal(t, "[POST] /orders 201", hook.LastEntry().Message, "access entries should reach hooks")
+	assert.Equal(t, float64(1), hook.LastEntry().Data["sample_rate"])
+}
+
+func TestCombinedLogLine_EscapesRequestLine(t *testing.T) {
+	e := echo.New()
+	r := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	r.Method = `GET"`
+	r.RequestURI = `/orders?q="x"`
+	r.RemoteAddr = "10.0.0.1:1234"
+	c := e.NewContext(r, httptest.NewRecorder())
+
+	line := combinedLogLine(c, http.StatusBadRequest, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+
+	assert.Equal(t, `10.0.0.1 - - [02/Jan/2026:03:04:05 +0000] "GET\" /orders?q=\"x\" HTTP/1.1" 400 - "-" "-"`, line)
+}
+
+func TestLogSchema_TraceFields(t *testing.T) {
+	otelFields := logrus.Fields{"trace_id": "t-1", "span_id": "s-1"}
+	newRelicFields := logrus.Fields{"trace.id": "t-1", "span.id": "s-1", "entity.name": "acme-login-dev"}
+
+	assert.Equal(t, otelFields, LogSchemaXecho.traceFields(otelFields))
+	assert.Equal(t, newRelicFields, LogSchemaXecho.traceFields(newRelicFields))
+	assert.Equal(t, logrus.Fields{"trace.id": "t-1", "span.id": "s-1"}, LogSchemaECS.traceFields(otelFields))
+	assert.Equal(t, newRelicFields, LogSchemaECS.traceFields(newRelicFields))
+	assert.Equal(t, logrus.Fields{
+		"logging.googleapis.com/trace":  "t-1",
+		"logging.googleapis.com/spanId": "s-1",
+	}, LogSchemaGCP.traceFields(otelFields))
+	assert.Equal(t, logrus.Fields{
+		"logging.googleapis.com/trace":  "t-1",
+		"logging.googleapis.com/spanId": "s-1",
+		"entity.name":                   "acme-login-dev",
+	}, LogSchemaGCP.traceFields(newRelicFields))
+}
+
+func TestLogSchema_CombinedRespectsLogLevel(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := logSchemaTestXecho(LogSchemaCombined, buffer)
+	x.logger.Logger.SetLevel(logrus.WarnLevel)
+	buffer.Reset()
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/orders").
+		JSON(`{"sku":"123"}`).
+		Expect(t).
+		Status(http.StatusCreated).
+		End()
+
+	assert.Empty(t, buffer.String())
+}
+
+func TestConfig_ValidateCombinedLogSchema(t *testing.T) {
+	conf := testConfig()
+	conf.LogSchema = LogSchemaCombined
+	conf.AccessLogBodies.Routes = []string{"/orders"}
+	conf.AccessLogSampling = []LogSamplingRule{{Route: "/orders", Rate: 0.1}}
+
+	err := conf.Validate()
+
+	assert.EqualError(t, err, "invalid xecho config: "+
+		"AccessLogBodies.Routes must be empty with the combined log schema, which can't log bodies; "+
+		"AccessLogSampling must be empty with the combined log schema, which can't log the sample rate")
+}
+
+func TestConfig_ValidateLogSchema(t *testing.T) {
+	conf := testConfig()
+	conf.LogSchema = "splunk"
+
+	err := conf.Validate()
+
+	assert.EqualError(t, err, "invalid xecho config: LogSchema must be one of [xecho ecs gcp combined]")
+}
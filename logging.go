@@ -6,7 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	"net/http/httputil"
+	"strings"
 
 	"github.com/labstack/echo"
 	"github.com/labstack/gommon/log"
@@ -16,7 +16,7 @@ import (
 func DebugLoggerMiddleware(isDebug bool) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return EchoHandler(func(c *Context) error {
-			if !isDebug {
+			if !isDebug && !c.IsDebug() {
 				return next(c)
 			}
 
@@ -54,9 +54,9 @@ func (drw *debugResponseWriter) WriteHeader(code int) {
 }
 
 func dumpRequest(c *Context) {
-	reqDump, err := httputil.DumpRequest(c.Request(), true)
+	reqDump, err := c.dumpConfig().dumpRequest(c.Request())
 	if err == nil {
-		c.Logger().Debugf("%s", string(reqDump))
+		c.Logger().Debugf("%s", reqDump)
 	}
 }
 
@@ -71,34 +71,66 @@ func dumpResponse(c *Context, drw *debugResponseWriter) {
 	}
 
 	body := res.Header.Get("Content-Type") != "text/html"
-	resDump, err := httputil.DumpResponse(res, body)
+	resDump, err := c.dumpConfig().dumpResponse(res, body)
 	if err == nil {
-		c.Logger().Debugf("%s", string(resDump))
+		c.Logger().Debugf("%s", resDump)
 	}
 }
 
+// HeaderLogConfig selects the headers added to the request-scoped logger and access logs
+type HeaderLogConfig struct {
+	// Request headers are logged on every line for the request
+	Request []string
+	// Response headers are logged on the access log line
+	Response []string
+	// Deny headers are never logged, even if listed in Request or Response
+	Deny []string
+	// Redact headers are logged as [REDACTED] when present
+	Redact []string
+}
+
+func DefaultHeaderLogConfig() HeaderLogConfig {
+	return HeaderLogConfig{
+		Request: []string{"User-Agent", "Referer", "X-Forwarded-For", "X-Forwarded-Proto"},
+	}
+}
+
+// fields returns the named headers keyed by their lower case names. Missing headers are logged as empty.
+func (h HeaderLogConfig) fields(header http.Header, names []string) logrus.Fields {
+	fields := logrus.Fields{}
+	for _, name := range names {
+		if containsFold(h.Deny, name) {
+			continue
+		}
+		value := header.Get(name)
+		if value != "" && containsFold(h.Redact, name) {
+			value = redacted
+		}
+		fields[strings.ToLower(name)] = value
+	}
+	return fields
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
 func requestScopeLogger(
 	logger *logrus.Entry,
 	r *http.Request,
 	route string,
 	ip string,
 	correlationID string,
+	headers HeaderLogConfig,
+	schema LogSchema,
 ) *Logger {
-	ctxLogger := logger.WithFields(logrus.Fields{
-		"correlation_id": correlationID,
-		"url":            r.RequestURI,
-		"route":          route,
-		"remote_addr":    r.RemoteAddr,
-		"method":         r.Method,
-		"ip":             ip,
-		"headers": logrus.Fields{
-			"host":              r.Host,
-			"user-agent":        r.UserAgent(),
-			"referer":           r.Referer(),
-			"x-forwarded-for":   r.Header.Get("X-Forwarded-For"),
-			"x-forwarded-proto": r.Header.Get("X-Forwarded-Proto"),
-		},
-	})
+	headerFields := headers.fields(r.Header, headers.Request)
+	ctxLogger := logger.WithFields(schema.requestFields(r, route, ip, correlationID, headerFields))
 	return &Logger{ctxLogger}
 }
 
@@ -0,0 +1,212 @@
+package xecho
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var defaultLatencyBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var defaultSizeBuckets = []float64{100, 1000, 10000, 100000, 1000000, 10000000}
+
+// Metrics records inbound and outbound HTTP metrics and exposes them in the Prometheus text format
+type Metrics struct {
+	now TimeProvider
+
+	requests       *metricVec
+	duration       *metricVec
+	size           *metricVec
+	inFlight       *metricVec
+	clientRequests *metricVec
+	clientDuration *metricVec
+}
+
+func NewMetrics(now TimeProvider) *Metrics {
+	return &Metrics{
+		now: now,
+		requests: newMetricVec("http_server_requests_total", "Total HTTP requests handled.",
+			metricCounter, nil, "method", "route", "status"),
+		duration: newMetricVec("http_server_request_duration_seconds", "Time taken to handle HTTP requests.",
+			metricHistogram, defaultLatencyBuckets, "method", "route", "status"),
+		size: newMetricVec("http_server_response_size_bytes", "Size of HTTP response bodies.",
+			metricHistogram, defaultSizeBuckets, "method", "route", "status"),
+		inFlight: newMetricVec("http_server_requests_in_flight", "HTTP requests currently being handled.",
+			metricGauge, nil, "method", "route"),
+		clientRequests: newMetricVec("http_client_requests_total", "Total outbound HTTP requests made.",
+			metricCounter, nil, "method", "host", "status"),
+		clientDuration: newMetricVec("http_client_request_duration_seconds", "Time taken by outbound HTTP requests.",
+			metricHistogram, defaultLatencyBuckets, "method", "host", "status"),
+	}
+}
+
+func MetricsMiddleware(m *Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return EchoHandler(func(c *Context) error {
+			c.metrics = m
+			method := c.Request().Method
+			route := c.Path()
+
+			m.inFlight.add(1, method, route)
+			defer m.inFlight.add(-1, method, route)
+
+			before := m.now()
+			err := next(c)
+			elapsed := m.now().Sub(before)
+
+			status := statusClass(c.Response().Status)
+			m.requests.add(1, method, route, status)
+			m.duration.observe(elapsed.Seconds(), method, route, status)
+			m.size.observe(float64(c.Response().Size), method, route, status)
+			return err
+		})
+	}
+}
+
+// Handler serves the recorded metrics in the Prometheus text format
+func (m *Metrics) Handler(c *Context) error {
+	c.Response().Header().Set(echo.HeaderContentType, prometheusContentType)
+	c.Response().WriteHeader(http.StatusOK)
+	return m.WritePrometheus(c.Response())
+}
+
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, vec := range []*metricVec{m.requests, m.duration, m.size, m.inFlight, m.clientRequests, m.clientDuration} {
+		vec.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (m *Metrics) observeClientRequest(r *http.Request, res *http.Response, elapsed time.Duration) {
+	status := "error"
+	if res != nil {
+		status = statusClass(res.StatusCode)
+	}
+	m.clientRequests.add(1, r.Method, r.URL.Host, status)
+	m.clientDuration.observe(elapsed.Seconds(), r.Method, r.URL.Host, status)
+}
+
+func statusClass(status int) string {
+	return fmt.Sprintf("%dxx", status/100)
+}
+
+type metricKind string
+
+const (
+	metricCounter   metricKind = "counter"
+	metricGauge     metricKind = "gauge"
+	metricHistogram metricKind = "histogram"
+)
+
+type metricVec struct {
+	mu         sync.Mutex
+	name       string
+	help       string
+	kind       metricKind
+	buckets    []float64
+	labelNames []string
+	series     map[string]*metricSeries
+}
+
+type metricSeries struct {
+	labelValues  []string
+	value        float64
+	bucketCounts []uint64
+	count        uint64
+}
+
+func newMetricVec(name, help string, kind metricKind, buckets []float64, labelNames ...string) *metricVec {
+	return &metricVec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		buckets:    buckets,
+		labelNames: labelNames,
+		series:     map[string]*metricSeries{},
+	}
+}
+
+// get must be called with the lock held
+func (v *metricVec) get(labelValues []string) *metricSeries {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: labelValues, bucketCounts: make([]uint64, len(v.buckets))}
+		v.series[key] = s
+	}
+	return s
+}
+
+func (v *metricVec) add(delta float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.get(labelValues).value += delta
+}
+
+func (v *metricVec) observe(value float64, labelValues ...string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s := v.get(labelValues)
+	s.value += value
+	s.count++
+	for i, bound := range v.buckets {
+		if value <= bound {
+			s.bucketCounts[i]++
+		}
+	}
+}
+
+func (v *metricVec) write(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if len(v.series) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(v.series))
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", v.name, v.help, v.name, v.kind)
+	for _, key := range keys {
+		s := v.series[key]
+		labels := v.labels(s.labelValues)
+		if v.kind != metricHistogram {
+			_, _ = fmt.Fprintf(w, "%s{%s} %s\n", v.name, labels, formatMetricValue(s.value))
+			continue
+		}
+		for i, bound := range v.buckets {
+			_, _ = fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d\n", v.name, labels, formatMetricValue(bound), s.bucketCounts[i])
+		}
+		_, _ = fmt.Fprintf(w, "%s_bucket{%s,le=\"+Inf\"} %d\n", v.name, labels, s.count)
+		_, _ = fmt.Fprintf(w, "%s_sum{%s} %s\n", v.name, labels, formatMetricValue(s.value))
+		_, _ = fmt.Fprintf(w, "%s_count{%s} %d\n", v.name, labels, s.count)
+	}
+}
+
+func (v *metricVec) labels(values []string) string {
+	pairs := make([]string, len(values))
+	for i, value := range values {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", v.labelNames[i], labelValueEscaper.Replace(value))
+	}
+	return strings.Join(pairs, ",")
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatMetricValue(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,85 @@
+package xecho
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_ServedInPrometheusFormat(t *testing.T) {
+	conf := testConfig()
+	conf.MetricsEnabled = true
+	conf.RoutePrefix = "/acme"
+	x := New(conf)
+	x.logger.Logger.SetOutput(&bytes.Buffer{})
+	x.Echo.GET("/acme/products/:id", EchoHandler(func(c *Context) error {
+		return c.String(http.StatusOK, "product")
+	}))
+
+	for _, path := range []string{"/acme/products/1", "/acme/products/2"} {
+		rec := httptest.NewRecorder()
+		x.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	x.Echo.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/acme/metrics", nil))
+
+	body := rec.Body.String()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, prometheusContentType, rec.Header().Get("Content-Type"))
+	assert.Contains(t, body, "# TYPE http_server_requests_total counter\n")
+	assert.Contains(t, body, `http_server_requests_total{method="GET",route="/acme/products/:id",status="2xx"} 2`)
+	assert.Contains(t, body, `http_server_request_duration_seconds_count{method="GET",route="/acme/products/:id",status="2xx"} 2`)
+	assert.Contains(t, body, `http_server_response_size_bytes_bucket{method="GET",route="/acme/products/:id",status="2xx",le="100"} 2`)
+	assert.Contains(t, body, `http_server_response_size_bytes_sum{method="GET",route="/acme/products/:id",status="2xx"} 14`)
+	assert.Contains(t, body, `http_server_requests_in_flight{method="GET",route="/acme/metrics"} 1`)
+}
+
+func TestMetrics_RouteNotAccessLogged(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.MetricsEnabled = true
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/metrics").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	assert.Empty(t, buffer.String())
+}
+
+func TestMetrics_ObserveClientRequest(t *testing.T) {
+	m := NewMetrics(time.Now)
+	u, _ := url.Parse("https://api.example.com/orders")
+	r := &http.Request{Method: http.MethodPost, URL: u}
+
+	m.observeClientRequest(r, &http.Response{StatusCode: http.StatusServiceUnavailable}, 200*time.Millisecond)
+	m.observeClientRequest(r, nil, 2*time.Second)
+
+	buffer := &bytes.Buffer{}
+	assert.NoError(t, m.WritePrometheus(buffer))
+	assert.Contains(t, buffer.String(), `http_client_requests_total{method="POST",host="api.example.com",status="5xx"} 1`)
+	assert.Contains(t, buffer.String(), `http_client_requests_total{method="POST",host="api.example.com",status="error"} 1`)
+	assert.Contains(t, buffer.String(), `http_client_request_duration_seconds_bucket{method="POST",host="api.example.com",status="5xx",le="0.25"} 1`)
+	assert.Contains(t, buffer.String(), `http_client_request_duration_seconds_bucket{method="POST",host="api.example.com",status="5xx",le="0.1"} 0`)
+}
+
+func TestMetrics_LabelValuesEscaped(t *testing.T) {
+	v := newMetricVec("test_total", "Test.", metricCounter, nil, "route")
+	v.add(1, "a\"b\\c\nd")
+
+	buffer := &bytes.Buffer{}
+	v.write(buffer)
+
+	assert.Equal(t, "# HELP test_total Test.\n# TYPE test_total counter\ntest_total{route=\"a\\\"b\\\\c\\nd\"} 1\n", buffer.String())
+}
@@ -0,0 +1,204 @@
+package xecho
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const otelInstrumentationName = "github.com/JSainsburyPLC/xecho"
+
+// NewOTelTracer traces every request as an OpenTelemetry server span created by provider.
+// Traces are continued from the W3C traceparent and tracestate headers of inbound requests,
+// and propagated on outbound requests made through Context.HttpClient.
+func NewOTelTracer(provider trace.TracerProvider) Tracer {
+	return &otelTracer{
+		provider:   provider,
+		tracer:     provider.Tracer(otelInstrumentationName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+type otelTracer struct {
+	provider   trace.TracerProvider
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+func (t *otelTracer) StartTransaction(name string, w http.ResponseWriter, r *http.Request) Transaction {
+	ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := t.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.route", name),
+			attribute.String("http.target", r.URL.RequestURI()),
+			attribute.String("http.user_agent", r.UserAgent()),
+			attribute.String("net.host.name", r.Host),
+		),
+	)
+	return &otelTransaction{
+		ctx:        ctx,
+		span:       span,
+		tracer:     t.tracer,
+		propagator: t.propagator,
+		w:          &otelResponseWriter{ResponseWriter: w},
+	}
+}
+
+// namesByRoute names server spans by route pattern, as the OpenTelemetry HTTP conventions require
+func (t *otelTracer) namesByRoute() bool {
+	return true
+}
+
+// Shutdown flushes spans if the provider supports it, as the SDK provider does
+func (t *otelTracer) Shutdown(timeout time.Duration) {
+	p, ok := t.provider.(interface {
+		Shutdown(ctx context.Context) error
+	})
+	if !ok {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	_ = p.Shutdown(ctx)
+}
+
+type otelTransaction struct {
+	ctx        context.Context
+	span       trace.Span
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+	w          *otelResponseWriter
+}
+
+func (t *otelTransaction) ResponseWriter() http.ResponseWriter {
+	return t.w
+}
+
+// Context returns the request context carrying the server span
+func (t *otelTransaction) Context() context.Context {
+	return t.ctx
+}
+
+func (t *otelTransaction) LogFields() logrus.Fields {
+	sc := t.span.SpanContext()
+	return logrus.Fields{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
+
+func (t *otelTransaction) AddAttribute(key string, value interface{}) error {
+	t.span.SetAttributes(otelAttribute(key, value))
+	return nil
+}
+
+func (t *otelTransaction) NoticeError(err error) error {
+	t.span.RecordError(err)
+	t.span.SetStatus(codes.Error, err.Error())
+	return nil
+}
+
+func (t *otelTransaction) StartExternalSegment(r *http.Request) ExternalSegment {
+	ctx, span := t.tracer.Start(t.ctx, fmt.Sprintf("HTTP %s", r.Method),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.url", r.URL.String()),
+			attribute.String("net.peer.name", r.URL.Host),
+		),
+	)
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+	return &otelExternalSegment{span: span}
+}
+
+func (t *otelTransaction) End() error {
+	status := t.w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	t.span.SetAttributes(attribute.Int("http.status_code", status))
+	if status >= http.StatusInternalServerError {
+		t.span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	t.span.End()
+	return nil
+}
+
+type otelExternalSegment struct {
+	span trace.Span
+}
+
+func (s *otelExternalSegment) LogFields() logrus.Fields {
+	sc := s.span.SpanContext()
+	return logrus.Fields{
+		"trace_id": sc.TraceID().String(),
+		"span_id":  sc.SpanID().String(),
+	}
+}
+
+func (s *otelExternalSegment) End(res *http.Response) error {
+	if res == nil {
+		s.span.SetStatus(codes.Error, "no response")
+	} else {
+		s.span.SetAttributes(attribute.Int("http.status_code", res.StatusCode))
+		if res.StatusCode >= http.StatusBadRequest {
+			s.span.SetStatus(codes.Error, http.StatusText(res.StatusCode))
+		}
+	}
+	s.span.End()
+	return nil
+}
+
+func otelAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	default:
+		return attribute.String(key, fmt.Sprintf("%v", v))
+	}
+}
+
+// otelResponseWriter records the response status for the server span
+type otelResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *otelResponseWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *otelResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *otelResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,111 @@
+package xecho
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestOTelTracer_ServerAndClientSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	conf := testConfig()
+	conf.Tracer = NewOTelTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	buffer := &bytes.Buffer{}
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+
+	var outboundTraceParent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		outboundTraceParent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer upstream.Close()
+	x.Echo.GET("/orders/:id", EchoHandler(func(c *Context) error {
+		assert.True(t, trace.SpanContextFromContext(c.Request().Context()).IsValid())
+		res, err := c.HttpClient.Get(upstream.URL)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		return c.NoContent(http.StatusOK)
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/orders/123", nil)
+	req.Header.Set("traceparent", testTraceParent)
+	req.Header.Set("tracestate", "vendor=value")
+	x.Echo.ServeHTTP(rec, req)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	client, server := spans[0], spans[1]
+
+	assert.Equal(t, "/orders/:id", server.Name)
+	assert.Equal(t, trace.SpanKindServer, server.SpanKind)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext.TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent.SpanID().String())
+	assert.Equal(t, "vendor=value", server.SpanContext.TraceState().String())
+	assert.Contains(t, server.Attributes, attribute.Int("http.status_code", http.StatusOK))
+	assert.Contains(t, server.Attributes, attribute.String("http.route", "/orders/:id"))
+
+	assert.Equal(t, "HTTP GET", client.Name)
+	assert.Equal(t, trace.SpanKindClient, client.SpanKind)
+	assert.Equal(t, server.SpanContext.SpanID(), client.Parent.SpanID())
+	assert.Contains(t, client.Attributes, attribute.Int("http.status_code", http.StatusNoContent))
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+client.SpanContext.SpanID().String()+"-01", outboundTraceParent)
+
+	assert.Contains(t, buffer.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, buffer.String(), `"span_id":"`+server.SpanContext.SpanID().String()+`"`)
+	assert.Regexp(t, `"msg":"Outgoing request: [^\n]*"span_id":"`+client.SpanContext.SpanID().String()+`"`,
+		buffer.String(), "the outbound line should have the client span ID")
+}
+
+func TestOTelTracer_LogFieldsFollowTheLogSchema(t *testing.T) {
+	conf := testConfig()
+	conf.Tracer = NewOTelTracer(sdktrace.NewTracerProvider())
+	conf.LogSchema = LogSchemaGCP
+	buffer := &bytes.Buffer{}
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders", func(c *Context) error {
+		c.Logger().Info("listing orders")
+		return c.NoContent(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+	req.Header.Set("traceparent", testTraceParent)
+	x.Echo.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, buffer.String(), `"logging.googleapis.com/trace":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, buffer.String(), `"logging.googleapis.com/spanId":"`)
+	assert.NotContains(t, buffer.String(), `"trace_id"`)
+}
+
+func TestOTelTracer_ErrorsRecordedOnSpan(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tracer := NewOTelTracer(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	tx := tracer.StartTransaction("/fail", httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	assert.NoError(t, tx.NoticeError(errors.New("boom")))
+	tx.ResponseWriter().WriteHeader(http.StatusInternalServerError)
+	assert.NoError(t, tx.End())
+	tracer.Shutdown(0)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 1)
+	assert.False(t, spans[0].Parent.IsValid())
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+}
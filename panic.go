@@ -0,0 +1,117 @@
+package xecho
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/labstack/echo"
+)
+
+// PanicReport describes a panic recovered while handling a request
+type PanicReport struct {
+	Value         string       `json:"value"`
+	Type          string       `json:"type"`
+	Method        string       `json:"method"`
+	Route         string       `json:"route"`
+	CorrelationID string       `json:"correlation_id"`
+	Stack         []StackFrame `json:"stack"`
+}
+
+// StackFrame is a single call in the panicking goroutine's stack, innermost first
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// PanicReporter is called with every recovered panic, e.g. to send it to a crash reporter
+type PanicReporter func(c *Context, report *PanicReport)
+
+type PanicHandlerConfig struct {
+	ErrorHandler ErrorHandlerFunc
+	// Reporter is optional
+	Reporter PanicReporter
+}
+
+func PanicHandlerMiddleware(errorHandler ErrorHandlerFunc) echo.MiddlewareFunc {
+	return PanicHandlerMiddlewareWithConfig(PanicHandlerConfig{ErrorHandler: errorHandler})
+}
+
+// PanicHandlerMiddlewareWithConfig recovers panics, logging a PanicReport in the "panic" field
+// and responding with ErrPanic
+func PanicHandlerMiddlewareWithConfig(conf PanicHandlerConfig) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			defer func() {
+				if r := recover(); r != nil {
+					cc := c.(*Context)
+					report := newPanicReport(cc, r)
+					cc.logger.WithField("panic", report).Errorf("[PANIC RECOVER] %s", report.Value)
+					if conf.Reporter != nil {
+						conf.Reporter(cc, report)
+					}
+
+					err, ok := r.(error)
+					if !ok {
+						err = fmt.Errorf("%v", r)
+					}
+					// panics are always noticed, whatever the status rules
+					handleError(conf.ErrorHandler, cc, ErrPanic.Wrap(recoveredPanic{err}), func(int) bool { return true })
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// recoveredPanic is the cause of the errors of recovered panics, which are logged with their report
+type recoveredPanic struct {
+	error
+}
+
+func (p recoveredPanic) Unwrap() error {
+	return p.error
+}
+
+// newPanicReport must be called by the deferred function that recovered r, so that the
+// panicking goroutine's stack can be captured
+func newPanicReport(c *Context, r interface{}) *PanicReport {
+	return &PanicReport{
+		Value:         fmt.Sprintf("%v", r),
+		Type:          fmt.Sprintf("%T", r),
+		Method:        c.Request().Method,
+		Route:         c.Path(),
+		CorrelationID: c.CorrelationID,
+		Stack:         panicStack(),
+	}
+}
+
+// panicStack returns the frames from where the panic was raised, skipping those of the runtime
+// and the recovering functions above it
+func panicStack() []StackFrame {
+	pcs := make([]uintptr, 64)
+	for {
+		n := runtime.Callers(1, pcs)
+		if n < len(pcs) {
+			pcs = pcs[:n]
+			break
+		}
+		pcs = make([]uintptr, len(pcs)*2)
+	}
+
+	var stack []StackFrame
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			stack = nil
+		} else if !strings.HasPrefix(frame.Function, "runtime.") || stack != nil {
+			stack = append(stack, StackFrame{Function: frame.Function, File: frame.File, Line: frame.Line})
+		}
+		if !more {
+			break
+		}
+	}
+	return stack
+}
@@ -0,0 +1,82 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPanicHandler_ReportsAndRespondsWithPanicCode(t *testing.T) {
+	tracer := NewRecordingTracer()
+	var reported *PanicReport
+	conf := testConfig()
+	conf.Tracer = tracer
+	conf.PanicReporter = func(c *Context, report *PanicReport) { reported = report }
+	x := New(conf)
+	buffer := &bytes.Buffer{}
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders/:id", func(c *Context) error {
+		var orders map[string]int
+		orders[c.Param("id")]++
+		return nil
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/orders/1").
+		Header("Correlation-Id", "abc-123").
+		Expect(t).
+		Status(http.StatusInternalServerError).
+		Body(`{"code": "PANIC", "detail": "Internal server error"}`).
+		End()
+
+	assert.Equal(t, "assignment to entry in nil map", reported.Value)
+	assert.Equal(t, "runtime.plainError", reported.Type)
+	assert.Equal(t, "GET", reported.Method)
+	assert.Equal(t, "/orders/:id", reported.Route)
+	assert.Equal(t, "abc-123", reported.CorrelationID)
+	assert.True(t, strings.HasSuffix(reported.Stack[0].Function, "TestPanicHandler_ReportsAndRespondsWithPanicCode.func2"),
+		"stack should start at the panicking handler, got %s", reported.Stack[0].Function)
+	assert.True(t, strings.HasSuffix(reported.Stack[0].File, "panic_test.go"))
+
+	var logged struct {
+		Panic PanicReport `json:"panic"`
+	}
+	var errorLines []string
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		if strings.Contains(line, `"level":"error"`) {
+			errorLines = append(errorLines, line)
+		}
+	}
+	assert.Len(t, errorLines, 1, "the panic should be logged once")
+	assert.NoError(t, json.Unmarshal([]byte(errorLines[0]), &logged))
+	assert.Equal(t, *reported, logged.Panic)
+
+	txn := tracer.Transactions()[0]
+	assert.Equal(t, "PANIC", txn.Attributes["errorCode"])
+	assert.Len(t, txn.Errors, 1)
+}
+
+func TestErrorHandler_LogsReturnedPanicErrors(t *testing.T) {
+	x := newTestXecho()
+	buffer := &bytes.Buffer{}
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders", func(c *Context) error {
+		return ErrPanic.WithParam("worker", "picker-1")
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/orders").
+		Expect(t).
+		Status(http.StatusInternalServerError).
+		End()
+
+	assert.Contains(t, buffer.String(), `"level":"error"`)
+	assert.Contains(t, buffer.String(), "worker: picker-1")
+}
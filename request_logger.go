@@ -2,6 +2,7 @@ package xecho
 
 import (
 	"math"
+	"math/rand"
 	"net/http"
 	"strings"
 	"time"
@@ -12,70 +13,192 @@ import (
 
 type TimeProvider func() time.Time
 
+type RequestLoggerConfig struct {
+	Now TimeProvider
+	// Skipper returns true for requests that should not be logged
+	Skipper func(c *Context) bool
+	// Sampling rules decide what fraction of 1xx-3xx requests are logged; requests matching no rule,
+	// and errors, are all logged
+	Sampling []LogSamplingRule
+	// SlowThreshold, when set, logs every request taking at least this long regardless of sampling
+	SlowThreshold time.Duration
+	// Random returns a number in [0, 1) and defaults to rand.Float64
+	Random func() float64
+	// Bodies opts routes in to logging their request and response bodies
+	Bodies BodyLogConfig
+	// Schema selects the access log field names or line format, LogSchemaXecho when empty.
+	// LogSchemaCombined lines are only written as such by the logger's LogSchema formatter.
+	Schema LogSchema
+}
+
+// LogSamplingRule logs Rate, a fraction between 0 and 1, of the requests it matches.
+// Empty fields match anything, and the first matching rule applies,
+// e.g. {Route: "/products/:id", StatusClass: "2xx", Rate: 0.01} logs 1% of successful requests.
+// 4xx and 5xx responses are always logged, whatever the rules, so Config.Validate rejects rules for them.
+type LogSamplingRule struct {
+	Method      string
+	Route       string
+	StatusClass string
+	Rate        float64
+}
+
+func (r LogSamplingRule) matches(method, route, statusClass string) bool {
+	return (r.Method == "" || strings.EqualFold(r.Method, method)) &&
+		(r.Route == "" || r.Route == route) &&
+		(r.StatusClass == "" || strings.EqualFold(r.StatusClass, statusClass))
+}
+
+// sampleRate returns the fraction of requests like this one that are logged
+func (conf RequestLoggerConfig) sampleRate(c *Context, status int, timeTaken time.Duration) float64 {
+	if conf.SlowThreshold > 0 && timeTaken >= conf.SlowThreshold {
+		return 1
+	}
+	if status >= http.StatusBadRequest {
+		return 1
+	}
+	for _, rule := range conf.Sampling {
+		if rule.matches(c.Request().Method, c.Path(), statusClass(status)) {
+			return rule.Rate
+		}
+	}
+	return 1
+}
+
 func RequestLoggerMiddleware(timeFn TimeProvider) echo.MiddlewareFunc {
+	return RequestLoggerMiddlewareWithConfig(RequestLoggerConfig{Now: timeFn, Skipper: skipHealthChecker})
+}
+
+func RequestLoggerMiddlewareWithConfig(conf RequestLoggerConfig) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
-		return EchoHandler(func(c *Context) error { return RequestLogger(c, next, timeFn) })
+		return EchoHandler(func(c *Context) error {
+			if conf.Skipper != nil && conf.Skipper(c) {
+				return next(c)
+			}
+			return logRequest(c, next, conf)
+		})
 	}
 }
 
 func RequestLogger(c *Context, next echo.HandlerFunc, time TimeProvider) error {
-	request := c.Request()
-	if request.URL.Path == "/health" && strings.Contains(request.UserAgent(), "HealthChecker") {
+	if skipHealthChecker(c) {
 		return next(c)
 	}
-	before := time()
+	return logRequest(c, next, RequestLoggerConfig{Now: time})
+}
+
+// AccessLogSkipper skips requests to any of routes, the registered route patterns, from a User-Agent
+// containing any of userAgents, or for which any of skippers returns true
+func AccessLogSkipper(routes []string, userAgents []string, skippers ...func(c *Context) bool) func(c *Context) bool {
+	skipRoutes := map[string]bool{}
+	for _, route := range routes {
+		skipRoutes[route] = true
+	}
+	return func(c *Context) bool {
+		if skipRoutes[c.Path()] {
+			return true
+		}
+		userAgent := c.Request().UserAgent()
+		for _, ua := range userAgents {
+			if strings.Contains(userAgent, ua) {
+				return true
+			}
+		}
+		for _, skip := range skippers {
+			if skip(c) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func skipHealthChecker(c *Context) bool {
+	request := c.Request()
+	return request.URL.Path == "/health" && strings.Contains(request.UserAgent(), "HealthChecker")
+}
+
+func logRequest(c *Context, next echo.HandlerFunc, conf RequestLoggerConfig) error {
+	request := c.Request()
+	before := conf.Now()
+	var bodies *capturedBodies
+	if conf.Bodies.logsRoute(c.Path()) {
+		bodies = &capturedBodies{conf: conf.Bodies}
+		bodies.request, bodies.requestTruncated = conf.Bodies.captureRequest(request)
+		bodies.response = &bodyCaptureWriter{ResponseWriter: c.Response().Writer, limit: conf.Bodies.MaxSize}
+		c.Response().Writer = bodies.response
+	}
 	lrw := &statefulResponseWriter{ResponseWriter: c.Response().Writer}
 	c.Response().Writer = lrw
 	err := next(c)
-	after := time()
+	after := conf.Now()
+
+	rate := conf.sampleRate(c, lrw.statusCode, after.Sub(before))
+	if rate < 1 {
+		random := conf.Random
+		if random == nil {
+			random = rand.Float64
+		}
+		if random() >= rate {
+			return err
+		}
+	}
+
 	logger, ok := c.Logger().(*Logger)
 	if !ok {
 		c.Logger().Infof("[%s] %s %d", request.Method, c.Path(), lrw.statusCode)
 		return err
 	}
-	logger.
-		WithFields(createMap(c, after.Sub(before), lrw, err)).
-		Infof("[%s] %s %d", request.Method, c.Path(), lrw.statusCode)
+	entry := logger.WithField("sample_rate", rate)
+	if conf.Schema == LogSchemaCombined {
+		// written as the whole line by the LogSchemaCombined formatter
+		entry = entry.WithField(combinedLineField, combinedLogLine(c, lrw.statusCode, before))
+	} else {
+		entry = entry.WithFields(createMap(c, after.Sub(before), lrw, err, c.headerLogConfig(), bodies, conf.Schema))
+	}
+	entry.Infof("[%s] %s %d", request.Method, c.Path(), lrw.statusCode)
 	return err
 }
 
-func createMap(c echo.Context, timeTaken time.Duration, lrw *statefulResponseWriter, err error) logrus.Fields {
+func createMap(
+	c echo.Context,
+	timeTaken time.Duration,
+	lrw *statefulResponseWriter,
+	err error,
+	headers HeaderLogConfig,
+	bodies *capturedBodies,
+	schema LogSchema,
+) logrus.Fields {
 	r := c.Request()
-	fields := logrus.Fields{
-		"duration_ms": milliseconds(timeTaken),
-		"request":     requestMap(r, c),
-		"response":    responseMap(c.Response(), lrw.statusCode),
-	}
-
-	if err != nil {
-		fields["error"] = err.Error()
+	request := requestMap(r, c, headers)
+	response := responseMap(c.Response(), lrw.statusCode, headers)
+	if bodies != nil {
+		bodies.addRequest(request, r)
+		bodies.addResponse(response, c.Response())
 	}
-
-	return fields
+	return schema.accessLogFields(c, timeTaken, lrw.statusCode, err, request, response)
 }
 
 func milliseconds(timeTaken time.Duration) int64 {
 	return int64(timeTaken) / 1e6
 }
 
-func responseMap(r *echo.Response, statusCode int) logrus.Fields {
-	return logrus.Fields{
+func responseMap(r *echo.Response, statusCode int, headers HeaderLogConfig) logrus.Fields {
+	fields := logrus.Fields{
 		"status_code":    statusCode,
 		"content_length": math.Max(float64(r.Size), 0),
 	}
+	if len(headers.Response) > 0 {
+		fields["headers"] = headers.fields(r.Header(), headers.Response)
+	}
+	return fields
 }
 
-func requestMap(r *http.Request, c echo.Context) logrus.Fields {
+func requestMap(r *http.Request, c echo.Context, headers HeaderLogConfig) logrus.Fields {
 	return logrus.Fields{
 		"method":         r.Method,
 		"host_name":      r.Host,
 		"query_params":   c.QueryParams(),
 		"Content-length": math.Max(float64(r.ContentLength), 0),
-		"headers": logrus.Fields{
-			"user-agent":        r.UserAgent(),
-			"referer":           r.Referer(),
-			"x-forwarded-for":   r.Header.Get("X-Forwarded-For"),
-			"x-forwarded-proto": r.Header.Get("X-Forwarded-Proto"),
-		},
+		"headers":        headers.fields(r.Header, headers.Request),
 	}
 }
@@ -5,11 +5,13 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/labstack/echo"
 	"github.com/sirupsen/logrus"
+	"github.com/steinfletcher/apitest"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -162,3 +164,181 @@ func createLogger(buffer *bytes.Buffer) *logrus.Logger {
 	log.Formatter = &logrus.JSONFormatter{}
 	return log
 }
+
+func TestRequestLogger_Sampling(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	logger := logrus.New()
+	logger.SetOutput(buffer)
+	clock := &testClock{now: time.Now()}
+	random := 0.5
+	e := echo.New()
+	e.Use(ContextMiddlewareWithConfig(ContextConfig{Logger: logger.WithField("test", true), Tracer: NoopTracer()}))
+	e.Use(RequestLoggerMiddlewareWithConfig(RequestLoggerConfig{
+		Now: clock.Now,
+		Sampling: []LogSamplingRule{
+			{Route: "/products/:id", StatusClass: "2xx", Rate: 0.01},
+			{Method: http.MethodGet, Route: "/basket", Rate: 0.6},
+			{Route: "/checkout", Rate: 0.01},
+		},
+		SlowThreshold: time.Second,
+		Random:        func() float64 { return random },
+	}))
+	e.GET("/products/:id", EchoHandler(func(c *Context) error {
+		if c.Param("id") == "slow" {
+			clock.Add(2 * time.Second)
+		}
+		if c.Param("id") == "missing" {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	}))
+	e.GET("/basket", EchoHandler(func(c *Context) error { return c.NoContent(http.StatusOK) }))
+	e.GET("/checkout", EchoHandler(func(c *Context) error { return c.NoContent(http.StatusInternalServerError) }))
+
+	sampleRates := func(path string) []float64 {
+		buffer.Reset()
+		apitest.New().Handler(e).Get(path).Expect(t).End()
+		var rates []float64
+		for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+			if line == "" {
+				continue
+			}
+			var fields map[string]interface{}
+			assert.NoError(t, json.Unmarshal([]byte(line), &fields))
+			rates = append(rates, fields["sample_rate"].(float64))
+		}
+		return rates
+	}
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	assert.Empty(t, sampleRates("/products/1"), "2xx sampled out")
+	assert.Equal(t, []float64{1}, sampleRates("/products/missing"), "4xx always logged")
+	assert.Equal(t, []float64{1}, sampleRates("/products/slow"), "slow requests always logged")
+	assert.Equal(t, []float64{0.6}, sampleRates("/basket"), "sampled in")
+	assert.Equal(t, []float64{1}, sampleRates("/checkout"), "5xx always logged, even by a rule without a status class")
+	random = 0.001
+	assert.Equal(t, []float64{0.01}, sampleRates("/products/1"), "sampled in")
+}
+
+func TestAccessLogSkipRules(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.RoutePrefix = "/acme"
+	conf.AccessLogSkipRoutes = []string{"/health", "/health/ready", "/internal/:name"}
+	conf.AccessLogSkipUserAgents = []string{"kube-probe"}
+	conf.AccessLogSkippers = []func(c *Context) bool{
+		func(c *Context) bool { return c.Request().Header.Get("X-Synthetic") == "true" },
+	}
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/internal/:name", func(c *Context) error { return c.NoContent(http.StatusOK) })
+	x.GET("/orders", func(c *Context) error { return c.NoContent(http.StatusOK) })
+
+	logged := func(path string, headers map[string]string) bool {
+		buffer.Reset()
+		apitest.New().Handler(x.Echo).Get(path).Headers(headers).Expect(t).End()
+		return buffer.Len() > 0
+	}
+
+	assert.False(t, logged("/acme/health", nil))
+	assert.False(t, logged("/acme/health/ready", nil))
+	assert.False(t, logged("/acme/internal/stats", nil))
+	assert.False(t, logged("/acme/orders", map[string]string{"User-Agent": "kube-probe/1.27"}))
+	assert.False(t, logged("/acme/orders", map[string]string{"X-Synthetic": "true"}))
+	assert.True(t, logged("/acme/orders", nil))
+}
+
+func TestAccessLogSkipRules_DefaultSkipsHealthCheckerOnly(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := New(testConfig())
+	x.logger.Logger.SetOutput(buffer)
+
+	logged := func(path string, userAgent string) bool {
+		buffer.Reset()
+		apitest.New().Handler(x.Echo).Get(path).Header("User-Agent", userAgent).Expect(t).End()
+		return buffer.Len() > 0
+	}
+
+	assert.False(t, logged("/health", "ELB-HealthChecker/2.0"))
+	assert.True(t, logged("/health", "curl/8.0"))
+	assert.True(t, logged("/health/ready", "ELB-HealthChecker/2.0"))
+	assert.True(t, logged("/health/live", "kube-probe/1.27"))
+}
+
+func TestRequestLogger_HeaderCapture(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	conf := testConfig()
+	conf.LogHeaders = HeaderLogConfig{
+		Request:  []string{"User-Agent", "X-Tenant", "X-Api-Key", "Authorization"},
+		Response: []string{"Content-Type", "Set-Cookie"},
+		Deny:     []string{"authorization"},
+		Redact:   []string{"x-api-key", "set-cookie"},
+	}
+	x := New(conf)
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders", func(c *Context) error {
+		c.Logger().Info("handling")
+		c.Response().Header().Set("Set-Cookie", "session=abc")
+		return c.JSON(http.StatusOK, map[string]string{})
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/orders").
+		Header("X-Tenant", "acme").
+		Header("X-Api-Key", "key-123").
+		Header("Authorization", "Bearer abc").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.NotContains(t, buffer.String(), "key-123")
+	assert.NotContains(t, buffer.String(), "Bearer abc")
+	assert.NotContains(t, buffer.String(), "session=abc")
+
+	var handlerLine struct {
+		Headers map[string]string `json:"headers"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &handlerLine))
+	assert.Equal(t, map[string]string{
+		"host":       "application",
+		"user-agent": "",
+		"x-tenant":   "acme",
+		"x-api-key":  "[REDACTED]",
+	}, handlerLine.Headers)
+
+	var accessLine struct {
+		Request struct {
+			Headers map[string]string `json:"headers"`
+		} `json:"request"`
+		Response struct {
+			Headers map[string]string `json:"headers"`
+		} `json:"response"`
+	}
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &accessLine))
+	assert.Equal(t, "acme", accessLine.Request.Headers["x-tenant"])
+	assert.Equal(t, "[REDACTED]", accessLine.Request.Headers["x-api-key"])
+	assert.NotContains(t, accessLine.Request.Headers, "authorization")
+	assert.Equal(t, map[string]string{
+		"content-type": "application/json; charset=UTF-8",
+		"set-cookie":   "[REDACTED]",
+	}, accessLine.Response.Headers)
+}
+
+func TestRequestLogger_DefaultHeaders(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := New(testConfig())
+	x.logger.Logger.SetOutput(buffer)
+	x.GET("/orders", func(c *Context) error { return c.NoContent(http.StatusOK) })
+
+	apitest.New().Handler(x.Echo).Get("/orders").Header("User-Agent", "test-agent").Expect(t).End()
+
+	var line struct {
+		Headers map[string]string `json:"headers"`
+	}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &line))
+	assert.Equal(t, "test-agent", line.Headers["user-agent"])
+	assert.Contains(t, line.Headers, "x-forwarded-for")
+}
@@ -0,0 +1,123 @@
+package xecho
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo"
+)
+
+// Middleware wraps a Handler, like echo.MiddlewareFunc but written against *Context
+type Middleware func(next Handler) Handler
+
+// Group registers routes under a common path prefix, wrapping each in the group's middleware.
+// Unlike echo.Group, middleware only runs for routes registered on the group, so unmatched
+// paths under the prefix still get the app's 404 and 405 responses.
+type Group struct {
+	echo       *echo.Echo
+	prefix     string
+	middleware []Middleware
+}
+
+// Group creates a route group at prefix under Config.RoutePrefix
+func (x *Xecho) Group(prefix string, m ...Middleware) *Group {
+	return x.root().Group(prefix, m...)
+}
+
+// Add registers a route for method and path under Config.RoutePrefix
+func (x *Xecho) Add(method, path string, h Handler, m ...Middleware) *echo.Route {
+	return x.root().Add(method, path, h, m...)
+}
+
+func (x *Xecho) GET(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodGet, path, h, m...)
+}
+
+func (x *Xecho) POST(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodPost, path, h, m...)
+}
+
+func (x *Xecho) PUT(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodPut, path, h, m...)
+}
+
+func (x *Xecho) PATCH(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodPatch, path, h, m...)
+}
+
+func (x *Xecho) DELETE(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodDelete, path, h, m...)
+}
+
+func (x *Xecho) HEAD(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodHead, path, h, m...)
+}
+
+func (x *Xecho) OPTIONS(path string, h Handler, m ...Middleware) *echo.Route {
+	return x.Add(http.MethodOptions, path, h, m...)
+}
+
+func (x *Xecho) root() *Group {
+	return &Group{echo: x.Echo, prefix: x.conf.RoutePrefix}
+}
+
+// Use adds middleware to routes registered on the group from now on
+func (g *Group) Use(m ...Middleware) {
+	g.middleware = append(g.middleware, m...)
+}
+
+// Group creates a nested group, which runs this group's middleware before its own
+func (g *Group) Group(prefix string, m ...Middleware) *Group {
+	return &Group{
+		echo:       g.echo,
+		prefix:     g.prefix + prefix,
+		middleware: g.with(m),
+	}
+}
+
+// Add registers a route for method and path under the group's prefix
+func (g *Group) Add(method, path string, h Handler, m ...Middleware) *echo.Route {
+	return g.echo.Add(method, g.prefix+path, EchoHandler(applyMiddleware(h, g.with(m))))
+}
+
+func (g *Group) GET(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodGet, path, h, m...)
+}
+
+func (g *Group) POST(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodPost, path, h, m...)
+}
+
+func (g *Group) PUT(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodPut, path, h, m...)
+}
+
+func (g *Group) PATCH(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodPatch, path, h, m...)
+}
+
+func (g *Group) DELETE(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodDelete, path, h, m...)
+}
+
+func (g *Group) HEAD(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodHead, path, h, m...)
+}
+
+func (g *Group) OPTIONS(path string, h Handler, m ...Middleware) *echo.Route {
+	return g.Add(http.MethodOptions, path, h, m...)
+}
+
+// with returns the group's middleware followed by m, without sharing the backing array
+func (g *Group) with(m []Middleware) []Middleware {
+	all := make([]Middleware, 0, len(g.middleware)+len(m))
+	all = append(all, g.middleware...)
+	return append(all, m...)
+}
+
+// applyMiddleware wraps h so that the first middleware runs first
+func applyMiddleware(h Handler, m []Middleware) Handler {
+	for i := len(m) - 1; i >= 0; i-- {
+		h = m[i](h)
+	}
+	return h
+}
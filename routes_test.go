@@ -0,0 +1,80 @@
+package xecho_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/JSainsburyPLC/xecho"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXecho_RoutesUseRoutePrefix(t *testing.T) {
+	x := xecho.New(config("/api"))
+	x.GET("/users/:id", func(c *xecho.Context) error {
+		return c.String(http.StatusOK, "user "+c.Param("id"))
+	})
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/api/users/1").
+		Expect(t).
+		Status(http.StatusOK).
+		Body("user 1").
+		End()
+	apitest.New().
+		Handler(x.Echo).
+		Get("/users/1").
+		Expect(t).
+		Status(http.StatusNotFound).
+		End()
+}
+
+func TestGroup_MiddlewareOrder(t *testing.T) {
+	x := xecho.New(config("/api"))
+	order := func(name string) xecho.Middleware {
+		return func(next xecho.Handler) xecho.Handler {
+			return func(c *xecho.Context) error {
+				c.Response().Header().Add("Order", name)
+				return next(c)
+			}
+		}
+	}
+	v1 := x.Group("/v1", order("group"))
+	v1.Use(order("use"))
+	admin := v1.Group("/admin", order("nested"))
+	admin.POST("/users", func(c *xecho.Context) error {
+		return c.NoContent(http.StatusCreated)
+	}, order("route"))
+
+	apitest.New().
+		Handler(x.Echo).
+		Post("/api/v1/admin/users").
+		Expect(t).
+		Status(http.StatusCreated).
+		Assert(func(res *http.Response, _ *http.Request) error {
+			assert.Equal(t, []string{"group", "use", "nested", "route"}, res.Header["Order"])
+			return nil
+		}).
+		End()
+}
+
+func TestGroup_UnmatchedPathIsNotFound(t *testing.T) {
+	x := xecho.New(config(""))
+	called := false
+	g := x.Group("/v1", func(next xecho.Handler) xecho.Handler {
+		return func(c *xecho.Context) error {
+			called = true
+			return next(c)
+		}
+	})
+	g.GET("/users", func(c *xecho.Context) error { return c.NoContent(http.StatusOK) })
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/v1/unknown").
+		Expect(t).
+		Status(http.StatusNotFound).
+		End()
+	assert.False(t, called)
+}
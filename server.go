@@ -0,0 +1,100 @@
+package xecho
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const tracerShutdownTimeout = 10 * time.Second
+
+// Run starts the server on address and blocks until SIGINT or SIGTERM is received,
+// then drains and shuts down gracefully. A second signal skips the drain delay. It returns early if the server fails to start or stops unexpectedly.
+func (x *Xecho) Run(address string) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	return x.run(address, signals)
+}
+
+func (x *Xecho) run(address string, signals <-chan os.Signal) error {
+	if err := x.Start(address); err != nil {
+		return err
+	}
+
+	select {
+	case sig := <-signals:
+		x.logger.Infof("Received signal %s, shutting down", sig)
+	case err := <-x.serveErr:
+		x.logger.WithError(err).Error("Server stopped unexpectedly")
+		x.shutdownTracer()
+		return err
+	}
+
+	x.Drain()
+	if x.conf.DrainDelay > 0 {
+		x.logger.Infof("Waiting %s for load balancers to stop sending traffic", x.conf.DrainDelay)
+		select {
+		case <-time.After(x.conf.DrainDelay):
+		case sig := <-signals:
+			x.logger.Infof("Received signal %s, skipping drain delay", sig)
+		}
+	}
+
+	ctx := context.Background()
+	if x.conf.DrainTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, x.conf.DrainTimeout)
+		defer cancel()
+	}
+	return x.Shutdown(ctx)
+}
+
+// Start binds the listener on address and serves requests in the background.
+// An error is returned only if the listener cannot be bound.
+func (x *Xecho) Start(address string) error {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	x.Echo.Listener = ln
+	x.serveErr = make(chan error, 1)
+
+	x.logger.Infof("Server listening on %s", ln.Addr())
+	go func() {
+		if err := x.Echo.Start(address); err != nil && err != http.ErrServerClosed {
+			x.serveErr <- err
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops accepting new connections, waits for in-flight requests to complete
+// until ctx is done and then flushes the tracer.
+func (x *Xecho) Shutdown(ctx context.Context) error {
+	x.logger.Info("Stopped accepting connections, draining in-flight requests")
+	err := x.Echo.Shutdown(ctx)
+	if err != nil {
+		x.logger.WithError(err).Error("Failed to drain in-flight requests")
+	} else {
+		x.logger.Info("In-flight requests drained")
+	}
+	if x.logLevels != nil {
+		x.logLevels.stop()
+	}
+	x.shutdownTracer()
+	x.logger.Info("Server shutdown complete")
+	return err
+}
+
+func (x *Xecho) shutdownTracer() {
+	if x.Tracer == nil {
+		return
+	}
+	x.logger.Info("Flushing tracer data")
+	x.Tracer.Shutdown(tracerShutdownTimeout)
+}
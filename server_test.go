@@ -0,0 +1,106 @@
+package xecho
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestXecho_ShutdownDrainsInFlightRequests(t *testing.T) {
+	x := newTestXecho()
+	started := make(chan struct{})
+	x.Echo.GET("/slow", EchoHandler(func(c *Context) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return c.String(http.StatusOK, "done")
+	}))
+	assert.NoError(t, x.Start("127.0.0.1:0"))
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		res, err := http.Get(fmt.Sprintf("http://%s/slow", x.Echo.Listener.Addr()))
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer res.Body.Close()
+		body, _ := ioutil.ReadAll(res.Body)
+		results <- result{status: res.StatusCode, body: string(body)}
+	}()
+	<-started
+
+	err := x.Shutdown(context.Background())
+
+	assert.NoError(t, err)
+	r := <-results
+	assert.NoError(t, r.err)
+	assert.Equal(t, http.StatusOK, r.status)
+	assert.Equal(t, "done", r.body)
+}
+
+func TestXecho_RunShutsDownOnSignal(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := newTestXecho()
+	x.logger.Logger.SetOutput(buffer)
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+
+	err := x.run("127.0.0.1:0", signals)
+
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "Received signal terminated, shutting down")
+	assert.Contains(t, buffer.String(), "Server shutdown complete")
+}
+
+func TestXecho_RunFailsToListen(t *testing.T) {
+	x := newTestXecho()
+
+	err := x.run("127.0.0.1:-1", make(chan os.Signal))
+
+	assert.Error(t, err)
+}
+
+func TestXecho_RunDrainsBeforeShutdown(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := newTestXecho()
+	x.conf.DrainDelay = 10 * time.Millisecond
+	x.logger.Logger.SetOutput(buffer)
+	signals := make(chan os.Signal, 1)
+	signals <- syscall.SIGTERM
+
+	err := x.run("127.0.0.1:0", signals)
+
+	assert.NoError(t, err)
+	assert.True(t, x.IsDraining())
+	assert.Contains(t, buffer.String(), "Draining: readiness now reports unavailable")
+	assert.Contains(t, buffer.String(), "Waiting 10ms for load balancers to stop sending traffic")
+}
+
+func newTestXecho() *Xecho {
+	x := New(testConfig())
+	x.logger.Logger.SetOutput(ioutil.Discard)
+	return x
+}
+
+func testConfig() Config {
+	conf := NewConfig()
+	conf.ProjectName = "acme"
+	conf.AppName = "login"
+	conf.EnvName = "dev"
+	conf.NewRelicLicense = "1111111111111111111111111111111111111111"
+	conf.NewRelicEnabled = false
+	return conf
+}
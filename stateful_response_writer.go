@@ -1,6 +1,11 @@
 package xecho
 
-import "net/http"
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
 
 type statefulResponseWriter struct {
 	http.ResponseWriter
@@ -11,3 +16,17 @@ func (lrw *statefulResponseWriter) WriteHeader(code int) {
 	lrw.ResponseWriter.WriteHeader(code)
 	lrw.statusCode = code
 }
+
+func (lrw *statefulResponseWriter) Flush() {
+	if f, ok := lrw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (lrw *statefulResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := lrw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
@@ -0,0 +1,246 @@
+package xecho
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/newrelic/go-agent"
+	"github.com/sirupsen/logrus"
+)
+
+// Tracer starts a Transaction for every inbound request. New Relic, no-op and in-memory
+// recording implementations are provided, and Config.Tracer selects which one an app uses.
+type Tracer interface {
+	StartTransaction(name string, w http.ResponseWriter, r *http.Request) Transaction
+	// Shutdown flushes any buffered data, waiting at most timeout
+	Shutdown(timeout time.Duration)
+}
+
+// Transaction traces a single inbound request
+type Transaction interface {
+	// ResponseWriter returns the writer the response should be written through, which
+	// may wrap the one passed to StartTransaction to record the response
+	ResponseWriter() http.ResponseWriter
+	AddAttribute(key string, value interface{}) error
+	NoticeError(err error) error
+	// StartExternalSegment traces an outbound request made while handling the inbound one
+	StartExternalSegment(r *http.Request) ExternalSegment
+	End() error
+}
+
+// TransactionLogFields is implemented by transactions that can identify themselves on log lines,
+// e.g. by trace and span ID. The fields are added to the request-scoped logger. External segments
+// may implement it too, to identify the log lines of their outbound request.
+type TransactionLogFields interface {
+	LogFields() logrus.Fields
+}
+
+// routeTracer is implemented by tracers whose transactions are named by route pattern, e.g.
+// /orders/:id, rather than by URL path. New Relic transactions keep the path names that existing
+// dashboards and alerts use.
+type routeTracer interface {
+	namesByRoute() bool
+}
+
+type ExternalSegment interface {
+	// End finishes the segment; res is nil if the request failed
+	End(res *http.Response) error
+}
+
+// NewRelicTracer traces transactions with the New Relic agent
+func NewRelicTracer(app newrelic.Application) Tracer {
+	return &newRelicTracer{app: app}
+}
+
+type newRelicTracer struct {
+	app newrelic.Application
+}
+
+func (t *newRelicTracer) StartTransaction(name string, w http.ResponseWriter, r *http.Request) Transaction {
+	return &newRelicTransaction{t.app.StartTransaction(name, w, r)}
+}
+
+func (t *newRelicTracer) Shutdown(timeout time.Duration) {
+	t.app.Shutdown(timeout)
+}
+
+type newRelicTransaction struct {
+	txn newrelic.Transaction
+}
+
+func (t *newRelicTransaction) ResponseWriter() http.ResponseWriter {
+	// the New Relic transaction wraps the response writer
+	return t.txn
+}
+
+func (t *newRelicTransaction) AddAttribute(key string, value interface{}) error {
+	return t.txn.AddAttribute(key, value)
+}
+
+func (t *newRelicTransaction) NoticeError(err error) error {
+	return t.txn.NoticeError(err)
+}
+
+// LogFields links log lines to the trace and entity, using the New Relic logs in context field names
+func (t *newRelicTransaction) LogFields() logrus.Fields {
+	return newRelicLogFields(t.txn.GetLinkingMetadata())
+}
+
+func (t *newRelicTransaction) StartExternalSegment(r *http.Request) ExternalSegment {
+	segment := newrelic.StartExternalSegment(t.txn, r)
+	// the span ID is the segment's while it is the active one
+	return &newRelicExternalSegment{segment: segment, logFields: newRelicLogFields(t.txn.GetLinkingMetadata())}
+}
+
+func (t *newRelicTransaction) End() error {
+	return t.txn.End()
+}
+
+type newRelicExternalSegment struct {
+	segment   *newrelic.ExternalSegment
+	logFields logrus.Fields
+}
+
+func (s *newRelicExternalSegment) LogFields() logrus.Fields {
+	return s.logFields
+}
+
+func (s *newRelicExternalSegment) End(res *http.Response) error {
+	s.segment.Response = res
+	return s.segment.End()
+}
+
+// newRelicLogFields leaves out empty identifiers, e.g. the span ID of an unsampled transaction
+func newRelicLogFields(md newrelic.LinkingMetadata) logrus.Fields {
+	fields := logrus.Fields{}
+	for key, value := range map[string]string{
+		"trace.id":    md.TraceID,
+		"span.id":     md.SpanID,
+		"entity.guid": md.EntityGUID,
+		"entity.name": md.EntityName,
+		"entity.type": md.EntityType,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
+
+// NoopTracer returns a Tracer that records nothing, for running without an APM agent
+func NoopTracer() Tracer {
+	return noopTracer{}
+}
+
+type noopTracer struct{}
+
+func (noopTracer) StartTransaction(_ string, w http.ResponseWriter, _ *http.Request) Transaction {
+	return noopTransaction{w: w}
+}
+
+func (noopTracer) Shutdown(_ time.Duration) {}
+
+type noopTransaction struct {
+	w http.ResponseWriter
+}
+
+func (t noopTransaction) ResponseWriter() http.ResponseWriter      { return t.w }
+func (noopTransaction) AddAttribute(_ string, _ interface{}) error { return nil }
+func (noopTransaction) NoticeError(_ error) error                  { return nil }
+func (noopTransaction) StartExternalSegment(_ *http.Request) ExternalSegment {
+	return noopExternalSegment{}
+}
+func (noopTransaction) End() error { return nil }
+
+type noopExternalSegment struct{}
+
+func (noopExternalSegment) End(_ *http.Response) error { return nil }
+
+// RecordingTracer keeps every transaction in memory, for asserting on tracing in tests
+type RecordingTracer struct {
+	mu           sync.Mutex
+	transactions []*RecordedTransaction
+}
+
+type RecordedTransaction struct {
+	Name       string
+	Attributes map[string]interface{}
+	Errors     []error
+	Segments   []*RecordedSegment
+	Ended      bool
+	mu         *sync.Mutex
+	w          http.ResponseWriter
+}
+
+type RecordedSegment struct {
+	Method     string
+	URL        string
+	StatusCode int
+	Ended      bool
+	mu         *sync.Mutex
+}
+
+func NewRecordingTracer() *RecordingTracer {
+	return &RecordingTracer{}
+}
+
+// Transactions returns the transactions started so far
+func (t *RecordingTracer) Transactions() []*RecordedTransaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]*RecordedTransaction(nil), t.transactions...)
+}
+
+func (t *RecordingTracer) StartTransaction(name string, w http.ResponseWriter, _ *http.Request) Transaction {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	txn := &RecordedTransaction{Name: name, Attributes: map[string]interface{}{}, mu: &t.mu, w: w}
+	t.transactions = append(t.transactions, txn)
+	return txn
+}
+
+func (t *RecordingTracer) Shutdown(_ time.Duration) {}
+
+func (t *RecordedTransaction) ResponseWriter() http.ResponseWriter {
+	return t.w
+}
+
+func (t *RecordedTransaction) AddAttribute(key string, value interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Attributes[key] = value
+	return nil
+}
+
+func (t *RecordedTransaction) NoticeError(err error) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Errors = append(t.Errors, err)
+	return nil
+}
+
+func (t *RecordedTransaction) StartExternalSegment(r *http.Request) ExternalSegment {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	segment := &RecordedSegment{Method: r.Method, URL: r.URL.String(), mu: t.mu}
+	t.Segments = append(t.Segments, segment)
+	return segment
+}
+
+func (t *RecordedTransaction) End() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.Ended = true
+	return nil
+}
+
+func (s *RecordedSegment) End(res *http.Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if res != nil {
+		s.StatusCode = res.StatusCode
+	}
+	s.Ended = true
+	return nil
+}
@@ -0,0 +1,157 @@
+package xecho
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent"
+	"github.com/sirupsen/logrus"
+	"github.com/steinfletcher/apitest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordingTracer_RecordsRequestAndOutboundCalls(t *testing.T) {
+	tracer := NewRecordingTracer()
+	conf := testConfig()
+	conf.Tracer = tracer
+	x := New(conf)
+	x.logger.Logger.SetOutput(&nullWriter{})
+	stock := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer stock.Close()
+	x.Echo.GET("/orders/:id", EchoHandler(func(c *Context) error {
+		res, err := c.HttpClient.Get(stock.URL + "/items")
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		return ErrNotFound
+	}))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	req.Header.Set("Correlation-Id", "corr-1")
+	x.Echo.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	txns := tracer.Transactions()
+	assert.Len(t, txns, 1)
+	assert.Equal(t, "/orders/1", txns[0].Name)
+	assert.True(t, txns[0].Ended)
+	assert.Equal(t, "/orders/:id", txns[0].Attributes["route"])
+	assert.Equal(t, "corr-1", txns[0].Attributes["correlationID"])
+	assert.Equal(t, "NOT_FOUND", txns[0].Attributes["errorCode"])
+	assert.Len(t, txns[0].Segments, 1)
+	assert.Equal(t, "GET", txns[0].Segments[0].Method)
+	assert.Equal(t, stock.URL+"/items", txns[0].Segments[0].URL)
+	assert.Equal(t, http.StatusOK, txns[0].Segments[0].StatusCode)
+	assert.True(t, txns[0].Segments[0].Ended)
+}
+
+func TestNoopTracer_RunsWithoutNewRelic(t *testing.T) {
+	conf := testConfig()
+	conf.NewRelicEnabled = true
+	conf.NewRelicLicense = ""
+	conf.Tracer = NoopTracer()
+
+	x, err := Create(conf)
+
+	assert.NoError(t, err)
+	assert.Nil(t, x.NewRelicApp)
+	x.logger.Logger.SetOutput(&nullWriter{})
+	x.Echo.GET("/ping", EchoHandler(func(c *Context) error {
+		assert.Nil(t, c.NewRelicTx)
+		c.AddTraceAttribute("ignored", true)
+		return c.String(http.StatusOK, "pong")
+	}))
+	apitest.New().
+		Handler(x.Echo).
+		Get("/ping").
+		Expect(t).
+		Status(http.StatusOK).
+		Body("pong").
+		End()
+}
+
+func TestNewRelicTracer_PopulatesDeprecatedFields(t *testing.T) {
+	x := newTestXecho()
+	x.Echo.GET("/ping", EchoHandler(func(c *Context) error {
+		assert.NotNil(t, c.NewRelicApp)
+		assert.NotNil(t, c.NewRelicTx)
+		return c.String(http.StatusOK, "pong")
+	}))
+
+	apitest.New().
+		Handler(x.Echo).
+		Get("/ping").
+		Expect(t).
+		Status(http.StatusOK).
+		End()
+}
+
+type nullWriter struct{}
+
+func (nullWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+func TestNewRelicTracer_AddsLogsInContextFields(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	x := New(testConfig())
+	x.logger.Logger.SetOutput(buffer)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+	x.GET("/orders", func(c *Context) error {
+		c.Logger().Info("listing orders")
+		res, err := c.HttpClient.Get(upstream.URL)
+		if err != nil {
+			return err
+		}
+		_ = res.Body.Close()
+		return c.NoContent(http.StatusOK)
+	})
+
+	apitest.New().Handler(x.Echo).Get("/orders").Expect(t).Status(http.StatusOK).End()
+
+	var lines []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buffer.String()), "\n") {
+		var fields map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &fields), line)
+		lines = append(lines, fields)
+	}
+	assert.Len(t, lines, 3, "handler, outbound and access log lines")
+	traceID := lines[0]["trace.id"]
+	assert.NotEmpty(t, traceID)
+	for _, line := range lines {
+		assert.Equal(t, traceID, line["trace.id"])
+		assert.Equal(t, "acme-login-dev", line["entity.name"])
+		assert.Equal(t, "SERVICE", line["entity.type"])
+		// unsampled, and not connected to New Relic, so without span ID or entity GUID
+		assert.NotContains(t, line, "span.id")
+		assert.NotContains(t, line, "entity.guid")
+	}
+}
+
+func TestNewRelicLogFields(t *testing.T) {
+	fields := newRelicLogFields(newrelic.LinkingMetadata{
+		TraceID:    "trace-1",
+		SpanID:     "span-1",
+		EntityName: "acme-login-dev",
+		EntityType: "SERVICE",
+		EntityGUID: "guid-1",
+		Hostname:   "vm",
+	})
+
+	assert.Equal(t, logrus.Fields{
+		"trace.id":    "trace-1",
+		"span.id":     "span-1",
+		"entity.guid": "guid-1",
+		"entity.name": "acme-login-dev",
+		"entity.type": "SERVICE",
+	}, fields)
+}
@@ -0,0 +1,279 @@
+package xecho
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FieldViolation describes why a single request field was rejected
+type FieldViolation struct {
+	Field    string `json:"field" example:"name"`
+	Location string `json:"location" example:"body"`
+	Message  string `json:"message" example:"is required"`
+}
+
+// validateRequest checks the `validate` tag of every field, including those of nested structs,
+// returning an *Error with status 422 if any fail. A tag is a comma separated list of rules:
+//
+//	required   must not be the zero value
+//	min=n      a number must be at least n, a string, slice or map at least n long
+//	max=n      a number must be at most n, a string, slice or map at most n long
+//	len=n      a string, slice or map must be exactly n long
+//	oneof=a b  must be one of the space separated values
+//
+// Rules other than required are skipped for nil pointers, so optional fields can be pointers.
+func validateRequest(v reflect.Value) error {
+	violations, err := validateStruct(v, "")
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return ErrValidationFailed.WithViolations(violations...)
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string) ([]FieldViolation, error) {
+	var violations []FieldViolation
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fv := v.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			nested, err := validateStruct(fv, prefix)
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, nested...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, location := fieldName(f)
+		field := prefix + name
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			for _, rule := range strings.Split(tag, ",") {
+				message, err := checkRule(fv, rule)
+				if err != nil {
+					return nil, fmt.Errorf("xecho: field %s: %s", field, err)
+				}
+				if message != "" {
+					violations = append(violations, FieldViolation{Field: field, Location: location, Message: message})
+					break
+				}
+			}
+		}
+
+		nested, err := validateNested(fv, field)
+		if err != nil {
+			return nil, err
+		}
+		violations = append(violations, nested...)
+	}
+	return violations, nil
+}
+
+func validateNested(v reflect.Value, field string) ([]FieldViolation, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if reflect.PtrTo(v.Type()).Implements(textUnmarshalerType) {
+			// scalar values such as time.Time
+			return nil, nil
+		}
+		return validateStruct(v, field+".")
+	case reflect.Slice, reflect.Array:
+		var violations []FieldViolation
+		for i := 0; i < v.Len(); i++ {
+			nested, err := validateNested(v.Index(i), fmt.Sprintf("%s[%d]", field, i))
+			if err != nil {
+				return nil, err
+			}
+			violations = append(violations, nested...)
+		}
+		return violations, nil
+	}
+	return nil, nil
+}
+
+// fieldName returns the name a field is bound from, so that violations match the request
+func fieldName(f reflect.StructField) (string, string) {
+	for _, source := range []struct{ tag, location string }{
+		{"json", "body"},
+		{"param", "path"},
+		{"query", "query"},
+		{"header", "header"},
+	} {
+		name := strings.Split(f.Tag.Get(source.tag), ",")[0]
+		if name != "" && name != "-" {
+			return name, source.location
+		}
+	}
+	return f.Name, "body"
+}
+
+// checkRule returns a message describing the violation, or an empty string if v passes the rule
+func checkRule(v reflect.Value, rule string) (string, error) {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+
+	if name == "required" {
+		if v.IsZero() {
+			return "is required", nil
+		}
+		return "", nil
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	switch name {
+	case "min", "max", "len":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid rule %q", rule)
+		}
+		size, unit, ok := measure(v)
+		if !ok || (name == "len" && unit == "") {
+			return "", fmt.Errorf("rule %q cannot be applied to %s", rule, v.Type())
+		}
+		switch {
+		case name == "min" && size < limit:
+			return fmt.Sprintf("must be at least %s%s", arg, unit), nil
+		case name == "max" && size > limit:
+			return fmt.Sprintf("must be at most %s%s", arg, unit), nil
+		case name == "len" && size != limit:
+			return fmt.Sprintf("must be exactly %s%s", arg, unit), nil
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		value := fmt.Sprintf("%v", v.Interface())
+		for _, option := range options {
+			if value == option {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of: %s", strings.Join(options, ", ")), nil
+	default:
+		return "", fmt.Errorf("unknown rule %q", rule)
+	}
+	return "", nil
+}
+
+// checkValidateTags returns an error for the first `validate` tag of t, or of the structs nested
+// in it, that has an unknown rule or a rule that can't be applied to its field, so that a bad tag is
+// found when a handler is registered rather than by a request
+func checkValidateTags(t reflect.Type, prefix string, seen map[reflect.Type]bool) error {
+	if seen[t] {
+		return nil
+	}
+	seen[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := checkValidateTags(f.Type, prefix, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name, _ := fieldName(f)
+		field := prefix + name
+		if tag := f.Tag.Get("validate"); tag != "" && tag != "-" {
+			for _, rule := range strings.Split(tag, ",") {
+				if err := checkRuleType(f.Type, rule); err != nil {
+					return fmt.Errorf("xecho: field %s: %s", field, err)
+				}
+			}
+		}
+
+		nested := f.Type
+		for nested.Kind() == reflect.Ptr || nested.Kind() == reflect.Slice || nested.Kind() == reflect.Array {
+			nested = nested.Elem()
+		}
+		if nested.Kind() == reflect.Struct && !reflect.PtrTo(nested).Implements(textUnmarshalerType) {
+			if err := checkValidateTags(nested, field+".", seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkRuleType returns the error checkRule would return for a value of type t
+func checkRuleType(t reflect.Type, rule string) error {
+	name, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, arg = rule[:i], rule[i+1:]
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch name {
+	case "required", "oneof":
+		return nil
+	case "min", "max", "len":
+		if _, err := strconv.ParseFloat(arg, 64); err != nil {
+			return fmt.Errorf("invalid rule %q", rule)
+		}
+		unit, ok := measureUnit(t.Kind())
+		if !ok || (name == "len" && unit == "") {
+			return fmt.Errorf("rule %q cannot be applied to %s", rule, t)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown rule %q", rule)
+}
+
+// measureUnit returns the unit measured by min, max and len for a kind, which is empty for numbers
+func measureUnit(kind reflect.Kind) (string, bool) {
+	switch kind {
+	case reflect.String:
+		return " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "", true
+	}
+	return "", false
+}
+
+// measure returns the value of a number, or the length of a string, slice or map with its unit
+func measure(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters", true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,70 @@
+package xecho
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type address struct {
+	Postcode string `json:"postcode" validate:"required"`
+}
+
+type customer struct {
+	Name      string         `json:"name" validate:"required,min=2"`
+	Tier      string         `json:"tier" validate:"oneof=gold silver"`
+	Age       *int           `json:"age" validate:"min=18,max=120"`
+	Codes     []string       `json:"codes" validate:"len=2"`
+	Home      address        `json:"home"`
+	Previous  []address      `json:"previous"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Extra     map[string]int `json:"extra" validate:"max=1"`
+}
+
+func TestValidateRequest_Violations(t *testing.T) {
+	age := 12
+	c := &customer{
+		Name:     "a",
+		Tier:     "bronze",
+		Age:      &age,
+		Codes:    []string{"x"},
+		Previous: []address{{Postcode: "N1"}, {}},
+		Extra:    map[string]int{"a": 1, "b": 2},
+	}
+
+	err := validateRequest(reflect.ValueOf(c).Elem())
+
+	assert.Equal(t, &Error{
+		Status: http.StatusUnprocessableEntity,
+		Code:   "VALIDATION_FAILED",
+		Detail: "Validation failed",
+		Violations: []FieldViolation{
+			{Field: "name", Location: "body", Message: "must be at least 2 characters"},
+			{Field: "tier", Location: "body", Message: "must be one of: gold, silver"},
+			{Field: "age", Location: "body", Message: "must be at least 18"},
+			{Field: "codes", Location: "body", Message: "must be exactly 2 items"},
+			{Field: "home.postcode", Location: "body", Message: "is required"},
+			{Field: "previous[1].postcode", Location: "body", Message: "is required"},
+			{Field: "extra", Location: "body", Message: "must be at most 1 items"},
+		},
+	}, err)
+}
+
+func TestValidateRequest_OptionalPointerSkipped(t *testing.T) {
+	c := &customer{Name: "Ann", Tier: "gold", Codes: []string{"x", "y"}, Home: address{Postcode: "N1"}}
+
+	assert.NoError(t, validateRequest(reflect.ValueOf(c).Elem()))
+}
+
+func TestValidateRequest_InvalidRule(t *testing.T) {
+	v := struct {
+		Name string `validate:"email"`
+	}{}
+
+	err := validateRequest(reflect.ValueOf(&v).Elem())
+
+	assert.EqualError(t, err, `xecho: field Name: unknown rule "email"`)
+}